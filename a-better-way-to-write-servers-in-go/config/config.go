@@ -1,6 +1,9 @@
 package config
 
-import "time"
+import (
+	"os"
+	"time"
+)
 
 type Config struct {
 	Port           string
@@ -8,6 +11,7 @@ type Config struct {
 	WriteTimeout   time.Duration
 	IdleTimeout    time.Duration
 	MaxHeaderBytes int
+	AdminToken     string // Bearer token for the admin router. Admin routes are disabled when empty.
 }
 
 func Parse() (Config, error) {
@@ -22,6 +26,15 @@ func Parse() (Config, error) {
 
 	// parse config values from env vars or flags
 	// ...
+	c.AdminToken = os.Getenv("ADMIN_TOKEN")
 
 	return c, nil
 }
+
+// Redacted returns a copy of the config that is safe to log or expose.
+func (c Config) Redacted() Config {
+	if c.AdminToken != "" {
+		c.AdminToken = "[REDACTED]"
+	}
+	return c
+}
@@ -2,4 +2,4 @@ module github/mtekmir/a-server
 
 go 1.18
 
-require github.com/go-chi/chi/v5 v5.0.8 // indirect
+require github.com/go-chi/chi/v5 v5.0.8
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// adminRoutes sets up the admin only routes. Every route is guarded by requireAdmin.
+func (s *Server) adminRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(s.requireAdmin)
+
+	// pprof.Index resolves named profiles from a "/debug/pprof/" path prefix,
+	// which we don't have under /admin, so named profiles get their own route.
+	r.HandleFunc("/debug/pprof/", pprof.Index)
+	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/debug/pprof/profile", s.limitSeconds(pprof.Profile, 30))
+	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/debug/pprof/trace", s.limitSeconds(pprof.Trace, 1))
+	r.HandleFunc("/debug/pprof/{profile}", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(chi.URLParam(r, "profile")).ServeHTTP(w, r)
+	})
+
+	r.Method("GET", "/debug/goroutines", handler(s.handleGoroutines))
+	r.Method("GET", "/debug/vars", expvar.Handler())
+	r.Method("GET", "/buildinfo", handler(s.handleBuildInfo))
+	r.Method("GET", "/config", handler(s.handleConfig))
+
+	return r
+}
+
+// requireAdmin only lets through the requests with a matching bearer token.
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(auth, "Bearer ")
+		if token == auth || subtle.ConstantTimeCompare([]byte(token), []byte(s.conf.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitSeconds caps the ?seconds= of the profiles that run for a while, def seconds when it isn't given,
+// one second below the write timeout, so e.g. the default 30s CPU profile ends within the default 30s
+// write timeout. Without it pprof rejects the longer profiles, or runs them past the server's write
+// deadline in newer Go versions.
+func (s *Server) limitSeconds(next http.HandlerFunc, def float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		max := (s.conf.WriteTimeout - time.Second).Seconds()
+		if s.conf.WriteTimeout == 0 || max < 1 {
+			next(w, r)
+			return
+		}
+
+		q := r.URL.Query()
+		sec, err := strconv.ParseFloat(q.Get("seconds"), 64)
+		if err != nil || sec <= 0 {
+			sec = def
+		}
+		if sec > max {
+			q.Set("seconds", strconv.Itoa(int(max)))
+			r.URL.RawQuery = q.Encode()
+		}
+		next(w, r)
+	}
+}
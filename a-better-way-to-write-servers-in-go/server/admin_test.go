@@ -0,0 +1,100 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github/mtekmir/a-server/config"
+	"github/mtekmir/a-server/server"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	s := server.New(config.Config{AdminToken: "secret-token"})
+
+	testCases := []struct {
+		desc           string
+		path           string
+		auth           string
+		expectedStatus int
+	}{
+		{desc: "no token", path: "/admin/config", expectedStatus: http.StatusUnauthorized},
+		{desc: "wrong token", path: "/admin/config", auth: "Bearer nope", expectedStatus: http.StatusUnauthorized},
+		{desc: "token without scheme", path: "/admin/config", auth: "secret-token", expectedStatus: http.StatusUnauthorized},
+		{desc: "config", path: "/admin/config", auth: "Bearer secret-token", expectedStatus: http.StatusOK},
+		{desc: "build info", path: "/admin/buildinfo", auth: "Bearer secret-token", expectedStatus: http.StatusOK},
+		{desc: "expvar", path: "/admin/debug/vars", auth: "Bearer secret-token", expectedStatus: http.StatusOK},
+		{desc: "goroutines", path: "/admin/debug/goroutines", auth: "Bearer secret-token", expectedStatus: http.StatusOK},
+		{desc: "pprof index", path: "/admin/debug/pprof/", auth: "Bearer secret-token", expectedStatus: http.StatusOK},
+		{desc: "pprof heap", path: "/admin/debug/pprof/heap", auth: "Bearer secret-token", expectedStatus: http.StatusOK},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tC.path, nil)
+			if tC.auth != "" {
+				req.Header.Set("Authorization", tC.auth)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tC.expectedStatus {
+				t.Errorf("Expected status to be %d. Got %d", tC.expectedStatus, rec.Code)
+			}
+			if strings.Contains(rec.Body.String(), "secret-token") {
+				t.Errorf("Response leaks the admin token. %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRoutes_DisabledWithoutToken(t *testing.T) {
+	s := server.New(config.Config{})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/config", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status to be %d. Got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestAdminRoutes_ProfileWithinWriteTimeout(t *testing.T) {
+	s := server.New(config.Config{AdminToken: "secret-token", WriteTimeout: 2 * time.Second})
+	srv := httptest.NewUnstartedServer(s)
+	srv.Config.WriteTimeout = 2 * time.Second
+	srv.Start()
+	defer srv.Close()
+
+	testCases := []struct {
+		desc string
+		path string
+	}{
+		{desc: "default seconds", path: "/admin/debug/pprof/profile"},
+		{desc: "seconds above write timeout", path: "/admin/debug/pprof/profile?seconds=60"},
+		{desc: "trace", path: "/admin/debug/pprof/trace?seconds=60"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req, err := http.NewRequest("GET", srv.URL+tC.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer secret-token")
+			start := time.Now()
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("Failed to request profile. %v", err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("Expected status to be %d. Got %d", http.StatusOK, res.StatusCode)
+			}
+			if took := time.Since(start); took > srv.Config.WriteTimeout {
+				t.Errorf("Expected the profile to end within the write timeout. Took %v", took)
+			}
+		})
+	}
+}
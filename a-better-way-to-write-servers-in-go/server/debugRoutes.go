@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"runtime/pprof"
+)
+
+func (s *Server) handleGoroutines(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+type buildInfo struct {
+	GoVersion  string            `json:"goVersion"`
+	Path       string            `json:"path"`
+	Version    string            `json:"version"`
+	Revision   string            `json:"revision,omitempty"`
+	RevisionAt string            `json:"revisionTime,omitempty"`
+	Modified   bool              `json:"modified"`
+	Deps       map[string]string `json:"deps"`
+	BuildFlags map[string]string `json:"buildSettings"`
+}
+
+func (s *Server) handleBuildInfo(w http.ResponseWriter, r *http.Request) error {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return errors.New("build info is not available")
+	}
+
+	info := buildInfo{
+		GoVersion:  bi.GoVersion,
+		Path:       bi.Main.Path,
+		Version:    bi.Main.Version,
+		Deps:       make(map[string]string, len(bi.Deps)),
+		BuildFlags: make(map[string]string, len(bi.Settings)),
+	}
+	for _, d := range bi.Deps {
+		v := d.Version
+		if d.Replace != nil {
+			v = d.Replace.Path + " " + d.Replace.Version
+		}
+		info.Deps[d.Path] = v
+	}
+	for _, st := range bi.Settings {
+		switch st.Key {
+		case "vcs.revision":
+			info.Revision = st.Value
+		case "vcs.time":
+			info.RevisionAt = st.Value
+		case "vcs.modified":
+			info.Modified = st.Value == "true"
+		}
+		info.BuildFlags[st.Key] = st.Value
+	}
+
+	return writeJSON(w, info)
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) error {
+	return writeJSON(w, s.conf.Redacted())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
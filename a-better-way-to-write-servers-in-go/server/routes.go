@@ -9,5 +9,10 @@ func (s *Server) SetupRoutes() {
 	// ...
 	r.Method("GET", "/status", handler(s.handleStatus))
 
+	if s.conf.AdminToken != "" {
+		r.Mount("/admin", s.adminRoutes())
+	}
+
+	s.Router = r
 	s.httpSrv.Handler = r
 }
@@ -14,12 +14,14 @@ import (
 type Server struct {
 	Db      *sql.DB
 	Router  chi.Router
+	conf    config.Config
 	httpSrv *http.Server
 }
 
 // New Initiates a new server.
 func New(conf config.Config) *Server {
 	s := &Server{
+		conf: conf,
 		httpSrv: &http.Server{
 			Addr:           fmt.Sprintf(":%s", conf.Port),
 			ReadTimeout:    conf.ReadTimeout,
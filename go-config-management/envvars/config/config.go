@@ -1,31 +1,112 @@
 package config
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"time"
+
+	"code.com/loader"
 )
 
+//go:generate go run code.com/loader/cmd/config doc -type=Config -secret-store -o=../CONFIG.md
+
+// Config is the config of the app, read from env vars.
 type Config struct {
-	DBHost     string // Host of database server
-	DBPort     string // ...
-	DBUser     string
-	DBPassword string
+	DBHost           string        `env:"DB_HOST" default:"localhost" validate:"nonempty"` // Host of database server
+	DBPort           int           `env:"DB_PORT" default:"5432" validate:"port"`          // Port of database server
+	DBUser           string        `env:"DB_USER" default:"postgres" required:"staging,prod" validate:"nonempty"`
+	DBPassword       loader.Secret `env:"DB_PASSWORD" default:"postgres" required:"staging,prod" validate:"nonempty"` // Can be read from DB_PASSWORD_FILE or be a secret:// reference
+	DBName           string        `env:"DB_NAME" default:"postgres" validate:"nonempty"`                             // Name of the database
+	DBSSLMode        string        `env:"DB_SSLMODE" default:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	DBSearchPath     string        `env:"DB_SEARCH_PATH"`                                    // Schemas to look up tables in, e.g. app,public
+	DBAppName        string        `env:"DB_APPLICATION_NAME" default:"envvars"`             // Shows up in pg_stat_activity
+	DBConnectTimeout time.Duration `env:"DB_CONNECT_TIMEOUT" default:"5s" validate:"min=0s"` // Rounded up to seconds, 0 waits forever
 	// ...
 }
 
-func Parse() Config {
-	c := Config{
-		DBHost:     getEnvOrDefault("DB_HOST", "localhost"),
-		DBPort:     getEnvOrDefault("DB_PORT", "5432"),
-		DBUser:     getEnvOrDefault("DB_USER", "postgres"),
-		DBPassword: getEnvOrDefault("DB_PASSWORD", "postgres"),
+// Parse parses the config from env vars. The defaults of the database credentials are only
+// good enough for local development, they have to be set explicitly when APP_ENV is staging or prod.
+// DB_PASSWORD can also be read from the file DB_PASSWORD_FILE points to, e.g. a Docker or Kubernetes secret,
+// or be a reference like secret://db/password to a secret store, see loader.SecretsFromEnv.
+// An existing DATABASE_URL sets the DB_ vars that aren't set themselves.
+// The error lists every missing or invalid field.
+func Parse() (Config, error) {
+	secrets, err := loader.SecretsFromEnv(os.LookupEnv)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid secret store config. %v", err)
+	}
+	env, err := withDatabaseURL(os.LookupEnv)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var c Config
+	if _, err := loader.Load(&c,
+		loader.WithEnv(env),
+		loader.WithProfile(os.Getenv("APP_ENV")),
+		loader.WithSecrets(secrets),
+	); err != nil {
+		return Config{}, fmt.Errorf("invalid config. %v", err)
 	}
-	return c
+	return c, nil
 }
 
-func getEnvOrDefault(key string, defaultVal string) string {
-	v := os.Getenv(key)
-	if v == "" {
-		return defaultVal
+// withDatabaseURL returns env with the DB_ vars that are unset looked up in the DATABASE_URL env var instead.
+func withDatabaseURL(env func(string) (string, bool)) (func(string) (string, bool), error) {
+	dsn, _ := env("DATABASE_URL")
+	if dsn == "" {
+		return env, nil
+	}
+	p, err := loader.ParsePostgres(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DATABASE_URL. %v", err)
+	}
+
+	vars := map[string]string{
+		"DB_HOST":             p.Host,
+		"DB_USER":             p.User,
+		"DB_PASSWORD":         p.Password.Value(),
+		"DB_NAME":             p.Database,
+		"DB_SSLMODE":          p.SSLMode,
+		"DB_SEARCH_PATH":      p.SearchPath,
+		"DB_APPLICATION_NAME": p.AppName,
 	}
-	return v
+	if p.Port != 0 {
+		vars["DB_PORT"] = strconv.Itoa(p.Port)
+	}
+	if p.ConnectTimeout != 0 {
+		vars["DB_CONNECT_TIMEOUT"] = p.ConnectTimeout.String()
+	}
+	return func(key string) (string, bool) {
+		if v, ok := env(key); ok && v != "" {
+			return v, true
+		}
+		// DB_PASSWORD_FILE wins over the password of the url.
+		if file, _ := env(key + "_FILE"); file == "" && vars[key] != "" {
+			return vars[key], true
+		}
+		return env(key)
+	}, nil
+}
+
+// Postgres returns the database connection config, whose URL and DSN methods build the connection strings.
+func (c Config) Postgres() loader.Postgres {
+	return loader.Postgres{
+		Host:           c.DBHost,
+		Port:           c.DBPort,
+		User:           c.DBUser,
+		Password:       c.DBPassword,
+		Database:       c.DBName,
+		SSLMode:        c.DBSSLMode,
+		SearchPath:     c.DBSearchPath,
+		AppName:        c.DBAppName,
+		ConnectTimeout: c.DBConnectTimeout,
+	}
+}
+
+// Dump writes the config to w with the password masked.
+func (c Config) Dump(w io.Writer) error {
+	return loader.Dump(w, c, nil)
 }
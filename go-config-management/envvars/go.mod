@@ -1,3 +1,15 @@
 module code.com
 
 go 1.17
+
+require (
+	code.com/loader v0.0.0-00010101000000-000000000000
+	github.com/google/go-cmp v0.5.8
+)
+
+require (
+	github.com/BurntSushi/toml v1.2.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace code.com/loader => ../loader
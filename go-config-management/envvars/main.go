@@ -1,8 +1,18 @@
 package main
 
-import "code.com/config"
+import (
+	"log"
+	"os"
 
-func main() {
-	config.Parse()
+	"code.com/config"
+)
 
+func main() {
+	c, err := config.Parse()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := c.Dump(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
 }
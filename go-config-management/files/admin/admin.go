@@ -0,0 +1,33 @@
+// Package admin serves the state of the scheduler, e.g. the run history of the jobs, to operators.
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Handler serves routes, keyed by path, e.g. /jobs, to the requests with the bearer token.
+// Every other request is answered with 401 Unauthorized, and every request with 404 Not Found if token is empty.
+func Handler(token string, routes map[string]http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	for path, h := range routes {
+		mux.Handle(path, h)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		auth := r.Header.Get("Authorization")
+		got := strings.TrimPrefix(auth, "Bearer ")
+		if got == auth || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,46 @@
+package admin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.com/admin"
+)
+
+func TestHandler(t *testing.T) {
+	jobs := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jobs":[]}`))
+	})
+
+	testCases := []struct {
+		desc           string
+		token          string
+		path           string
+		auth           string
+		expectedStatus int
+	}{
+		{desc: "no token", token: "secret-token", path: "/jobs", expectedStatus: http.StatusUnauthorized},
+		{desc: "wrong token", token: "secret-token", path: "/jobs", auth: "Bearer nope", expectedStatus: http.StatusUnauthorized},
+		{desc: "token without scheme", token: "secret-token", path: "/jobs", auth: "secret-token", expectedStatus: http.StatusUnauthorized},
+		{desc: "jobs", token: "secret-token", path: "/jobs", auth: "Bearer secret-token", expectedStatus: http.StatusOK},
+		{desc: "unknown route", token: "secret-token", path: "/nope", auth: "Bearer secret-token", expectedStatus: http.StatusNotFound},
+		{desc: "disabled without token", path: "/jobs", auth: "Bearer ", expectedStatus: http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			h := admin.Handler(tC.token, map[string]http.Handler{"/jobs": jobs})
+
+			req := httptest.NewRequest("GET", tC.path, nil)
+			if tC.auth != "" {
+				req.Header.Set("Authorization", tC.auth)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tC.expectedStatus {
+				t.Errorf("Expected status to be %d. Got %d", tC.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -1,53 +1,133 @@
 package config
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"code.com/cron"
+	"code.com/loader"
 )
 
+//go:generate go run code.com/loader/cmd/config doc -type=Config,CronConfigs -example=inventoryCron -secret-store -o=../CONFIG.md
+
+// Config is the config of the scheduler, read from flags and env vars.
 type Config struct {
 	// ...
-	CronConfigs CronConfigs
-}
+	Env string `env:"APP_ENV" flag:"env" default:"local" usage:"environment to run in. The cron config overlay of the environment, e.g. cron_config.prod.json for prod, is merged into the cron config file."`
+
+	CronConfigFile   string        `flag:"cron-config-file" default:"cron_config.json" usage:"path of cron config file. .json, .yaml and .toml files are supported."`
+	CronConfigReload time.Duration `flag:"cron-config-reload" default:"10s" usage:"how often to check the cron config file for changes. 0 disables reloading."`
+	CronConfigs      CronConfigs
+
+	SMTPAddr      string        `flag:"smtp-addr" usage:"host:port of the smtp server to send job notifications through."` // Notifications are logged when empty
+	SMTPFrom      string        `flag:"smtp-from" default:"cron@localhost" usage:"sender address of job notifications."`
+	NotifyWebhook string        `flag:"notify-webhook" usage:"url to post job notifications to instead of email."`
+	DatabaseURL   loader.Secret `flag:"database-url" usage:"postgres url to hold the job locks shared by replicas."` // Locks are in memory when empty
+
+	AdminAddr  string        `flag:"admin-addr" usage:"host:port to serve the jobs, their run history and the cron config reloads on, e.g. localhost:8081."` // Disabled when empty
+	AdminToken loader.Secret `env:"ADMIN_TOKEN" flag:"admin-token" usage:"bearer token of the admin requests."`
 
-type CronConfigs struct {
-	InventoryCron CronConfig `json:"inventoryCron"`
-	InvoicesCron  CronConfig `json:"invoicesCron"`
+	Args []string // Arguments left after the flags, e.g. a command
 }
 
+// CronConfigs are the cron jobs keyed by name. Each job needs a function registered with the same name,
+// see scheduler.Registry. They are read strictly: unknown keys are errors.
+type CronConfigs map[string]CronConfig
+
+// CronConfig is the config of a job. The usage tags are the descriptions in the JSON Schema, see Schema.
 type CronConfig struct {
-	Schedule    string   `json:"schedule"`
-	Description string   `json:"desc"`
-	Disabled    bool     `json:"disabled"`
-	NotifyEmail []string `json:"notifyEmail"`
+	Schedule    string   `json:"schedule" required:"true" example:"30 0 * * *" usage:"5-field cron expression (minute hour day-of-month month day-of-week) or a descriptor like @daily."`
+	Description string   `json:"desc" usage:"what the job does."`
+	Disabled    bool     `json:"disabled" usage:"skips the job without removing it."`
+	NotifyEmail []string `json:"notifyEmail" usage:"addresses notified when a run fails."`
+	TimeZone    string   `json:"timeZone" usage:"IANA time zone the schedule is evaluated in, e.g. Europe/Istanbul. Defaults to the scheduler's location."`
+
+	Timeout time.Duration     `json:"timeout" validate:"min=1s" usage:"runs are canceled after timeout, e.g. 10m. No timeout when unset."`
+	Retries int               `json:"retries" validate:"min=0,max=10" usage:"how many times failed runs are retried."`
+	Args    map[string]string `json:"args" usage:"passed to the job function on every run."`
 }
 
-func Parse() (Config, error) {
-	// ...
+// schedulePattern is the rough shape of cron expressions, so editors can flag schedules that can't be valid.
+const schedulePattern = `^\s*(@[A-Za-z]+|[0-9A-Za-z*/,-]+(\s+[0-9A-Za-z*/,-]+){4})\s*$`
 
-	cronConfPath := flag.String("cron-config-file", "cron_config.json", "path of cron config file")
-	flag.Parse()
+// Schema returns the JSON Schema of cron config files. Editors can autocomplete a file that points to it
+// with a "$schema" key, see the schema command.
+func Schema() map[string]interface{} {
+	s := loadSchema()
+	job := s["additionalProperties"].(map[string]interface{})
+	job["properties"].(map[string]interface{})["schedule"].(map[string]interface{})["pattern"] = schedulePattern
+	return s
+}
 
-	file, err := os.Open(*cronConfPath)
-	if err != nil {
-		return Config{}, fmt.Errorf("failed to open config file. %v", err)
+// loadSchema is the schema files are validated against when loaded. It has no schedule pattern since
+// Validate checks schedules with cron.Parse, whose errors say what is wrong with them.
+func loadSchema() map[string]interface{} {
+	s := loader.Schema(CronConfigs{})
+	s["title"] = "Cron config"
+	s["description"] = "Cron jobs keyed by name. Each job needs a function registered with the same name."
+	return s
+}
+
+// Names returns the job names sorted.
+func (cc CronConfigs) Names() []string {
+	names := make([]string, 0, len(cc))
+	for name := range cc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the schedule and time zone of every job, disabled ones included.
+// The returned error lists every invalid job.
+func (cc CronConfigs) Validate() error {
+	var errs []string
+	for _, name := range cc.Names() {
+		c := cc[name]
+		if _, err := cron.Parse(c.Schedule); err != nil {
+			errs = append(errs, fmt.Sprintf("%s.schedule: %v", name, err))
+		}
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			errs = append(errs, fmt.Sprintf("%s.timeZone: %v", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d invalid cron config(s):\n\t%s", len(errs), strings.Join(errs, "\n\t"))
 	}
-	bb, err := ioutil.ReadAll(file)
+	return nil
+}
+
+// Parse parses the config from the command line flags and env vars, see ParseArgs.
+func Parse() (Config, error) {
+	return ParseArgs(os.Args[1:], os.LookupEnv)
+}
+
+// ParseArgs parses the config from the flags in args and the env vars looked up with env, then loads
+// the cron config file. Values like secret://db/url, e.g. of -database-url, are read from the secret store
+// configured by env, see loader.SecretsFromEnv. The arguments left after the flags, e.g. a command, are in Args.
+// The error is flag.ErrHelp if -help was given.
+func ParseArgs(args []string, env func(string) (string, bool)) (Config, error) {
+	fs := flag.NewFlagSet("files", flag.ContinueOnError)
+	secrets, err := loader.SecretsFromEnv(env)
 	if err != nil {
-		return Config{}, fmt.Errorf("failed to read config file. %v", err)
+		return Config{}, fmt.Errorf("invalid secret store config. %v", err)
 	}
 
-	var cc CronConfigs
-	if err := json.Unmarshal(bb, &cc); err != nil {
-		return Config{}, fmt.Errorf("failed to unmarshal config file. %v", err)
+	var c Config
+	if _, err := loader.Load(&c, loader.WithEnv(env), loader.WithFlags(fs, args), loader.WithSecrets(secrets)); err != nil {
+		return Config{}, err
 	}
+	c.Args = fs.Args()
 
-	conf := Config{
-		CronConfigs: cc,
+	cc, err := loadCronConfigs(c.CronConfigFile, c.Env, env)
+	if err != nil {
+		return Config{}, err
 	}
+	c.CronConfigs = cc
 
-	return conf, nil
+	return c, nil
 }
@@ -1,32 +1,53 @@
 package config_test
 
 import (
-	"os"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"code.com/config"
 	"github.com/google/go-cmp/cmp"
 )
 
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
 func TestParse(t *testing.T) {
+	t.Parallel()
+
 	expectedConf := config.Config{
+		Env:              "test",
+		CronConfigFile:   "testdata/cron_config.test.json",
+		CronConfigReload: 10 * time.Second,
 		CronConfigs: config.CronConfigs{
-			InventoryCron: config.CronConfig{
+			"inventoryCron": {
 				Schedule:    "30 0 * * *",
 				Description: "Cron to calculate inventory stats",
 				Disabled:    false,
 				NotifyEmail: []string{"jdoe@gmail.com"},
 			},
-			InvoicesCron: config.CronConfig{
+			"invoicesCron": {
 				Schedule:    "10 0 * * *",
 				Description: "Cron to generate invoices",
 				Disabled:    true,
 			},
 		},
+		SMTPFrom: "cron@localhost",
+		Args:     []string{"next", "-n=3"},
 	}
 
-	os.Args[1] = "-cron-config-file=testdata/cron_config.test.json"
-	c, err := config.Parse()
+	c, err := config.ParseArgs(
+		[]string{"-cron-config-file=testdata/cron_config.test.json", "next", "-n=3"},
+		env(map[string]string{"APP_ENV": "test"}),
+	)
 	if err != nil {
 		t.Fatalf("failed to parse config. %v", err)
 	}
@@ -35,3 +56,112 @@ func TestParse(t *testing.T) {
 		t.Errorf("Configs are different (-want +got):\n%s", diff)
 	}
 }
+
+func TestCronConfigsValidate(t *testing.T) {
+	cc := config.CronConfigs{
+		"inventoryCron": {Schedule: "30 0 * *"},
+		"invoicesCron":  {Schedule: "10 0 * * *", TimeZone: "Mars/Olympus", Disabled: true},
+	}
+
+	err := cc.Validate()
+	if err == nil {
+		t.Fatal("Expected Validate() to fail")
+	}
+
+	for _, want := range []string{
+		`inventoryCron.schedule: cron "30 0 * *": expected 5 fields`,
+		`invoicesCron.timeZone: unknown time zone Mars/Olympus`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q. Got %v", want, err)
+		}
+	}
+}
+
+func TestCronConfigsStrict(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		content     string
+		expectedErr string
+	}{
+		{
+			desc:        "misspelled key",
+			content:     `{"inventoryCron": {"schedule": "30 0 * * *", "retry": 3}}`,
+			expectedErr: `inventoryCron: unknown key "retry", did you mean retries?`,
+		},
+		{
+			desc:        "missing schedule",
+			content:     `{"inventoryCron": {"desc": "Cron to calculate inventory stats"}}`,
+			expectedErr: "inventoryCron.schedule: is required",
+		},
+		{
+			desc:        "schedule with too few fields",
+			content:     `{"inventoryCron": {"schedule": "30 0 * *"}}`,
+			expectedErr: `inventoryCron.schedule: cron "30 0 * *": expected 5 fields (minute hour day-of-month month day-of-week), got 4`,
+		},
+		{
+			desc:        "too many retries",
+			content:     `{"inventoryCron": {"schedule": "30 0 * * *", "retries": 20}}`,
+			expectedErr: "inventoryCron.retries: must be at most 10, got 20",
+		},
+		{
+			desc:        "invalid timeout",
+			content:     `{"inventoryCron": {"schedule": "30 0 * * *", "timeout": "soon"}}`,
+			expectedErr: `inventoryCron.timeout: expected a duration string like "1m30s", got "soon"`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cron_config.json")
+			if err := ioutil.WriteFile(path, []byte(tC.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := config.NewWatcher(path, "", env(nil), time.Hour)
+			if err == nil || !strings.Contains(err.Error(), tC.expectedErr) {
+				t.Errorf("Expected error to contain %q. Got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCronConfigFile(t *testing.T) {
+	// the checked in config must parse with strict mode.
+	w, err := config.NewWatcher("../cron_config.json", "", env(nil), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Current()) == 0 {
+		t.Error("Expected the checked in config to have jobs")
+	}
+}
+
+func TestSchemaFile(t *testing.T) {
+	// the checked in schema editors use must match Schema.
+	bb, err := ioutil.ReadFile("../cron_config.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := json.MarshalIndent(config.Schema(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(expected)+"\n", string(bb)); diff != "" {
+		t.Errorf("cron_config.schema.json is outdated, run `go run . schema > cron_config.schema.json` (-want +got):\n%s", diff)
+	}
+}
+
+func TestSchema_SchedulePattern(t *testing.T) {
+	job := config.Schema()["additionalProperties"].(map[string]interface{})
+	schedule := job["properties"].(map[string]interface{})["schedule"].(map[string]interface{})
+	re, err := regexp.Compile(schedule["pattern"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for expr, expected := range map[string]bool{"30 0 * * *": true, "@daily": true, "*/15 9-17 * * mon-fri": true, "30 0 * *": false, "": false} {
+		if re.MatchString(expr) != expected {
+			t.Errorf("Expected %q to match the schedule pattern: %v", expr, expected)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"code.com/loader"
+)
+
+// Watcher polls a cron config file and the overlay of its env and reloads them whenever their content changes.
+// Invalid changes are logged and ignored, so the last good config stays in effect.
+// Every changed field is logged and kept in the History of the watcher.
+type Watcher struct {
+	path     string
+	env      string
+	interval time.Duration
+	lookup   func(string) (string, bool)
+	history  *loader.History
+
+	// reloadMu serializes reloads so subscribers see changes in order.
+	reloadMu sync.Mutex
+
+	mu      sync.Mutex
+	current CronConfigs
+	sum     [sha256.Size]byte
+	subs    []func(old, new CronConfigs)
+}
+
+// NewWatcher loads the cron config file at path with the overlay of env and initiates a watcher
+// that checks them every interval. The ${VAR} variables of the files are expanded with lookup on every load,
+// which should be the one the config was parsed with, e.g. os.LookupEnv.
+func NewWatcher(path, env string, lookup func(string) (string, bool), interval time.Duration) (*Watcher, error) {
+	w := &Watcher{path: path, env: env, interval: interval, lookup: lookup, history: loader.NewHistory(100)}
+	sum, err := w.checksum()
+	if err != nil {
+		return nil, err
+	}
+	cc, err := loadCronConfigs(path, env, lookup)
+	if err != nil {
+		return nil, err
+	}
+	w.current, w.sum = cc, sum
+	return w, nil
+}
+
+// checksum returns the checksum of the content of the file and its overlay.
+func (w *Watcher) checksum() ([sha256.Size]byte, error) {
+	h := sha256.New()
+	for i, path := range []string{w.path, loader.OverlayPath(w.path, w.env)} {
+		if path == "" {
+			continue
+		}
+		bb, err := ioutil.ReadFile(path)
+		if i > 0 && os.IsNotExist(err) { // overlays are optional
+			bb, err = nil, nil
+		}
+		if err != nil {
+			return [sha256.Size]byte{}, fmt.Errorf("failed to read config file. %v", err)
+		}
+		// the length separates the files, so moving content from one to the other changes the sum.
+		fmt.Fprintf(h, "%d:", len(bb))
+		h.Write(bb)
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// Current returns the last good cron configs. They are shared with the subscribers, so they must not be modified.
+func (w *Watcher) Current() CronConfigs {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// History returns the changes of the last 100 reloads.
+func (w *Watcher) History() *loader.History {
+	return w.history
+}
+
+// Subscribe registers fn to be called with the old and new cron configs after every change.
+// They must not be modified.
+// Subscribers are called one at a time, in the order the changes happened.
+func (w *Watcher) Subscribe(fn func(old, new CronConfigs)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// Run checks the file every interval until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := w.Reload(); err != nil {
+			log.Printf("failed to reload %s, keeping the last good config. %v", w.path, err)
+		}
+	}
+}
+
+// Reload reloads the file if its content changed and notifies the subscribers if the cron configs did.
+// changed reports whether they were notified. On error the current configs are kept.
+func (w *Watcher) Reload() (changed bool, err error) {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	sum, err := w.checksum()
+	if err != nil {
+		return false, err
+	}
+
+	w.mu.Lock()
+	same := bytes.Equal(sum[:], w.sum[:])
+	w.mu.Unlock()
+	if same {
+		return false, nil
+	}
+
+	// the file may change again before it is loaded, the next reload picks that up since the sum won't match.
+	cc, err := loadCronConfigs(w.path, w.env, w.lookup)
+	if err != nil {
+		return false, err
+	}
+
+	w.mu.Lock()
+	old := w.current
+	w.current, w.sum = cc, sum
+	subs := append([]func(old, new CronConfigs){}, w.subs...)
+	w.mu.Unlock()
+
+	// e.g. whitespace or key order changes.
+	if reflect.DeepEqual(old, cc) {
+		return false, nil
+	}
+	changes, _ := loader.Diff(old, cc) // only fails for configs of different types.
+	loader.LogChanges(log.Default(), w.path, changes)
+	w.history.Record(w.path, changes)
+	for _, fn := range subs {
+		fn(old, cc)
+	}
+	return true, nil
+}
+
+// loadCronConfigs reads the cron config file at path, merges the overlay of env into it if there is one,
+// expands its ${VAR} variables with lookup and validates the result against the schema and Validate.
+func loadCronConfigs(path, env string, lookup func(string) (string, bool)) (CronConfigs, error) {
+	var cc CronConfigs
+	if _, err := loader.Load(&cc, loader.WithEnv(lookup), loader.WithFile(path), loader.WithOverlay(loader.OverlayPath(path, env)), loader.WithSchema(loadSchema())); err != nil {
+		return CronConfigs{}, err
+	}
+	if err := cc.Validate(); err != nil {
+		return CronConfigs{}, fmt.Errorf("invalid config file %s. %v", path, err)
+	}
+	return cc, nil
+}
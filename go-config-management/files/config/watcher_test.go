@@ -0,0 +1,212 @@
+package config_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"code.com/config"
+	"code.com/loader"
+	"github.com/google/go-cmp/cmp"
+)
+
+const cronConfigJSON = `{
+  "inventoryCron": {"schedule": "30 0 * * *", "disabled": %s},
+  "invoicesCron": {"schedule": "%s"}
+}`
+
+func writeCronConfig(t *testing.T, path, disabled, schedule string) {
+	t.Helper()
+
+	// write to a temp file and rename so the watcher never reads a partial file.
+	tmp := path + ".tmp"
+	content := []byte(fmt.Sprintf(cronConfigJSON, disabled, schedule))
+	if err := ioutil.WriteFile(tmp, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type change struct {
+	old, new config.CronConfigs
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cron_config.json")
+	writeCronConfig(t, path, "false", "10 0 * * *")
+
+	w, err := config.NewWatcher(path, "", env(nil), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []change
+	w.Subscribe(func(old, new config.CronConfigs) {
+		changes = append(changes, change{old, new})
+	})
+
+	// unchanged file.
+	if changed, err := w.Reload(); err != nil || changed {
+		t.Errorf("Expected no change. changed: %v, err: %v", changed, err)
+	}
+
+	writeCronConfig(t, path, "true", "10 0 * * *")
+	if changed, err := w.Reload(); err != nil || !changed {
+		t.Fatalf("Expected a change. changed: %v, err: %v", changed, err)
+	}
+	if len(changes) != 1 || changes[0].old["inventoryCron"].Disabled || !changes[0].new["inventoryCron"].Disabled {
+		t.Errorf("Expected subscribers to get the old and new configs. Got %+v", changes)
+	}
+
+	// invalid schedule, the last good config is kept.
+	writeCronConfig(t, path, "false", "10 0 * *")
+	if _, err := w.Reload(); err == nil {
+		t.Error("Expected reloading an invalid config to fail")
+	}
+	if !w.Current()["inventoryCron"].Disabled || len(changes) != 1 {
+		t.Errorf("Expected the last good config to be kept. Got %+v", w.Current())
+	}
+
+	// fixed again.
+	writeCronConfig(t, path, "false", "10 0 * * *")
+	if changed, err := w.Reload(); err != nil || !changed {
+		t.Fatalf("Expected a change. changed: %v, err: %v", changed, err)
+	}
+	if w.Current()["inventoryCron"].Disabled || len(changes) != 2 || !changes[1].old["inventoryCron"].Disabled {
+		t.Errorf("Expected the fixed config to be loaded. Got %+v", changes)
+	}
+
+	// only the reloads that changed the config are in the history.
+	var got [][]loader.Change
+	for _, r := range w.History().Reloads() {
+		if r.Source != path {
+			t.Errorf("Expected the source of reloads to be %s. Got %s", path, r.Source)
+		}
+		got = append(got, r.Changes)
+	}
+	expected := [][]loader.Change{
+		{{Field: "inventoryCron.Disabled", Old: "false", New: "true"}},
+		{{Field: "inventoryCron.Disabled", Old: "true", New: "false"}},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("history is different (-want +got):\n%s", diff)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cron_config.json")
+	writeCronConfig(t, path, "false", "10 0 * * *")
+
+	w, err := config.NewWatcher(path, "", env(nil), time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan change, 10)
+	w.Subscribe(func(old, new config.CronConfigs) {
+		changes <- change{old, new}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// readers race with the reloads.
+	go func() {
+		for ctx.Err() == nil {
+			w.Current()
+		}
+	}()
+
+	writeCronConfig(t, path, "false", "20 0 * * *")
+	select {
+	case c := <-changes:
+		if c.new["invoicesCron"].Schedule != "20 0 * * *" {
+			t.Errorf("Expected the new schedule. Got %+v", c.new)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the change")
+	}
+}
+
+func TestNewWatcher_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cron_config.json")
+	writeCronConfig(t, path, "false", "10 0 * *")
+
+	if _, err := config.NewWatcher(path, "", env(nil), time.Second); err == nil {
+		t.Error("Expected an invalid config to fail")
+	}
+}
+
+func TestWatcher_Lookup(t *testing.T) {
+	t.Setenv("OPS_EMAIL", "env@code.com")
+	lookup := env(map[string]string{"OPS_EMAIL": "ops@code.com"})
+
+	path := filepath.Join(t.TempDir(), "cron_config.json")
+	write := func(disabled string) {
+		t.Helper()
+		content := `{"inventoryCron": {"schedule": "30 0 * * *", "disabled": ` + disabled + `, "notifyEmail": ["${OPS_EMAIL}"]}}`
+		if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("false")
+
+	w, err := config.NewWatcher(path, "", lookup, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("true")
+	if changed, err := w.Reload(); err != nil || !changed {
+		t.Fatalf("Expected a change. changed: %v, err: %v", changed, err)
+	}
+
+	// reloads expand the variables like the first load, not from the process env.
+	expected := config.CronConfigs{
+		"inventoryCron": {Schedule: "30 0 * * *", Disabled: true, NotifyEmail: []string{"ops@code.com"}},
+	}
+	if diff := cmp.Diff(expected, w.Current()); diff != "" {
+		t.Errorf("configs are different (-want +got):\n%s", diff)
+	}
+}
+
+func TestWatcher_Overlay(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cron_config.json")
+	writeCronConfig(t, path, "false", "10 0 * * *")
+
+	w, err := config.NewWatcher(path, "prod", env(nil), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Current()["invoicesCron"].Schedule != "10 0 * * *" {
+		t.Errorf("Expected the base config without an overlay. Got %+v", w.Current())
+	}
+
+	// adding the overlay is a change like any other.
+	overlay := `{"inventoryCron": {"disabled": true, "notifyEmail": ["ops@gmail.com"]}, "invoicesCron": null}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "cron_config.prod.json"), []byte(overlay), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := w.Reload(); err != nil || !changed {
+		t.Fatalf("Expected a change. changed: %v, err: %v", changed, err)
+	}
+
+	expected := config.CronConfigs{
+		"inventoryCron": {Schedule: "30 0 * * *", Disabled: true, NotifyEmail: []string{"ops@gmail.com"}},
+	}
+	if diff := cmp.Diff(expected, w.Current()); diff != "" {
+		t.Errorf("configs are different (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,262 @@
+// Package cron parses standard 5-field cron expressions and computes their fire times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// Day of month and day of week are OR'ed when both are restricted,
+	// so we need to know if either of them started with a '*', steps like */2 included.
+	domStar, dowStar bool
+}
+
+type bounds struct {
+	name      string
+	min, max  uint
+	names     map[string]uint
+	namesHint string
+}
+
+var (
+	minutes = bounds{name: "minute", min: 0, max: 59}
+	hours   = bounds{name: "hour", min: 0, max: 23}
+	doms    = bounds{name: "day of month", min: 1, max: 31}
+	months  = bounds{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}, namesHint: "JAN-DEC"}
+	// 7 is accepted as sunday as well.
+	dows = bounds{name: "day of week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}, namesHint: "SUN-SAT"}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseError describes why an expression failed to parse.
+type ParseError struct {
+	Expr  string // The expression being parsed
+	Field string // Name of the invalid field, empty if the expression as a whole is invalid
+	Pos   int    // 1-based position of the invalid token in Expr
+	Msg   string
+}
+
+func (e *ParseError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("cron %q: %s", e.Expr, e.Msg)
+	}
+	return fmt.Sprintf("cron %q: %s field at position %d: %s", e.Expr, e.Field, e.Pos, e.Msg)
+}
+
+// Parse parses a 5-field cron expression (minute hour day-of-month month day-of-week)
+// or one of the descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly.
+// The returned error is a *ParseError.
+func Parse(expr string) (Schedule, error) {
+	orig := expr
+	if strings.HasPrefix(strings.TrimSpace(expr), "@") {
+		d, ok := descriptors[strings.ToLower(strings.TrimSpace(expr))]
+		if !ok {
+			return Schedule{}, &ParseError{Expr: orig, Msg: fmt.Sprintf("unknown descriptor %q", strings.TrimSpace(expr))}
+		}
+		expr = d
+	}
+
+	fields, offsets := splitFields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, &ParseError{
+			Expr: orig,
+			Msg:  fmt.Sprintf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields)),
+		}
+	}
+
+	var (
+		s   Schedule
+		err error
+	)
+	p := parser{expr: orig}
+	if s.minute, err = p.parseField(fields[0], offsets[0], minutes); err != nil {
+		return Schedule{}, err
+	}
+	if s.hour, err = p.parseField(fields[1], offsets[1], hours); err != nil {
+		return Schedule{}, err
+	}
+	if s.dom, err = p.parseField(fields[2], offsets[2], doms); err != nil {
+		return Schedule{}, err
+	}
+	if s.month, err = p.parseField(fields[3], offsets[3], months); err != nil {
+		return Schedule{}, err
+	}
+	if s.dow, err = p.parseField(fields[4], offsets[4], dows); err != nil {
+		return Schedule{}, err
+	}
+	// fold 7 into 0, both mean sunday.
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*") || fields[2] == "?"
+	s.dowStar = strings.HasPrefix(fields[4], "*") || fields[4] == "?"
+
+	return s, nil
+}
+
+// splitFields splits expr on whitespace and returns the fields with their 0-based offsets.
+func splitFields(expr string) ([]string, []int) {
+	var (
+		fields  []string
+		offsets []int
+		start   = -1
+	)
+	for i, r := range expr + " " {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				fields = append(fields, expr[start:i])
+				offsets = append(offsets, start)
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	return fields, offsets
+}
+
+type parser struct {
+	expr string
+}
+
+func (p parser) errorf(b bounds, offset int, format string, args ...interface{}) error {
+	return &ParseError{Expr: p.expr, Field: b.name, Pos: offset + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseField parses a comma separated list of values, ranges and steps into a bitset.
+// offset is the position of field in the expression, used for error reporting.
+func (p parser) parseField(field string, offset int, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return 0, p.errorf(b, offset, "empty list item, allowed values are %s", b.allowed())
+		}
+
+		rng, step := part, uint(1)
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 0)
+			if err != nil || n == 0 || uint(n) > b.max {
+				return 0, p.errorf(b, offset+i+1, "invalid step %q, must be between 1 and %d", part[i+1:], b.max)
+			}
+			rng, step = part[:i], uint(n)
+		}
+
+		var lo, hi uint
+		switch {
+		case rng == "*" || rng == "?":
+			lo, hi = b.min, b.max
+		case strings.Contains(rng, "-"):
+			i := strings.Index(rng, "-")
+			var err error
+			if lo, err = p.parseValue(rng[:i], offset, b); err != nil {
+				return 0, err
+			}
+			if hi, err = p.parseValue(rng[i+1:], offset+i+1, b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, p.errorf(b, offset, "invalid range %q, start is greater than end", rng)
+			}
+		default:
+			v, err := p.parseValue(rng, offset, b)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			// "5/15" means starting at 5 every 15.
+			if step > 1 {
+				hi = b.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+		offset += len(part) + 1
+	}
+	return bits, nil
+}
+
+func (p parser) parseValue(s string, offset int, b bounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 0)
+	if err != nil {
+		return 0, p.errorf(b, offset, "invalid value %q, allowed values are %s", s, b.allowed())
+	}
+	if uint(n) < b.min || uint(n) > b.max {
+		return 0, p.errorf(b, offset, "value %d out of range, allowed values are %s", n, b.allowed())
+	}
+	return uint(n), nil
+}
+
+// allowed describes the values the field accepts.
+func (b bounds) allowed() string {
+	s := fmt.Sprintf("%d-%d", b.min, b.max)
+	if b.namesHint != "" {
+		s += " or " + b.namesHint
+	}
+	return s
+}
+
+// Next returns the first fire time strictly after t, in t's location.
+// It returns the zero time if the schedule never fires within the next five years (e.g. "0 0 30 2 *").
+func (s Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
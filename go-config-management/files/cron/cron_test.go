@@ -0,0 +1,155 @@
+package cron_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"code.com/cron"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2022, 5, 23, 13, 29, 16, 0, time.UTC) // monday
+
+	testCases := []struct {
+		desc     string
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{desc: "every minute", expr: "* * * * *", from: from, expected: time.Date(2022, 5, 23, 13, 30, 0, 0, time.UTC)},
+		{desc: "daily at 00:30", expr: "30 0 * * *", from: from, expected: time.Date(2022, 5, 24, 0, 30, 0, 0, time.UTC)},
+		{desc: "strictly after", expr: "30 13 * * *", from: time.Date(2022, 5, 23, 13, 30, 0, 0, time.UTC), expected: time.Date(2022, 5, 24, 13, 30, 0, 0, time.UTC)},
+		{desc: "steps", expr: "*/15 * * * *", from: from, expected: time.Date(2022, 5, 23, 13, 30, 0, 0, time.UTC)},
+		{desc: "step from value", expr: "5/20 * * * *", from: from, expected: time.Date(2022, 5, 23, 13, 45, 0, 0, time.UTC)},
+		{desc: "ranges and lists", expr: "0 9-17/4,22 * * *", from: from, expected: time.Date(2022, 5, 23, 17, 0, 0, 0, time.UTC)},
+		{desc: "day of week names", expr: "0 0 * * sat,sun", from: from, expected: time.Date(2022, 5, 28, 0, 0, 0, 0, time.UTC)},
+		{desc: "sunday as 7", expr: "0 0 * * 7", from: from, expected: time.Date(2022, 5, 29, 0, 0, 0, 0, time.UTC)},
+		{desc: "month names", expr: "0 0 1 jan *", from: from, expected: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{desc: "day of month or day of week", expr: "0 0 1 * fri", from: from, expected: time.Date(2022, 5, 27, 0, 0, 0, 0, time.UTC)},
+		{desc: "day of month step and day of week", expr: "0 0 */2 * fri", from: from, expected: time.Date(2022, 5, 27, 0, 0, 0, 0, time.UTC)},
+		{desc: "day of month and day of week step", expr: "0 0 1 * */2", from: from, expected: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{desc: "leap day", expr: "0 0 29 2 *", from: from, expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{desc: "never", expr: "0 0 30 2 *", from: from, expected: time.Time{}},
+		{desc: "@daily", expr: "@daily", from: from, expected: time.Date(2022, 5, 24, 0, 0, 0, 0, time.UTC)},
+		{desc: "@hourly", expr: "@hourly", from: from, expected: time.Date(2022, 5, 23, 14, 0, 0, 0, time.UTC)},
+		{desc: "@weekly", expr: "@weekly", from: from, expected: time.Date(2022, 5, 29, 0, 0, 0, 0, time.UTC)},
+		{desc: "@monthly", expr: "@monthly", from: from, expected: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{desc: "@yearly", expr: "@yearly", from: from, expected: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, err := cron.Parse(tC.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) = %v", tC.expr, err)
+			}
+
+			if got := s.Next(tC.from); !got.Equal(tC.expected) {
+				t.Errorf("Expected next run to be %s. Got %s", tC.expected, got)
+			}
+		})
+	}
+}
+
+func TestNext_TimeZone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Skipf("time zone data is not available. %v", err)
+	}
+
+	s, err := cron.Parse("30 0 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.Next(time.Date(2022, 5, 23, 20, 0, 0, 0, time.UTC).In(loc))
+	expected := time.Date(2022, 5, 24, 0, 30, 0, 0, loc)
+	if !got.Equal(expected) {
+		t.Errorf("Expected next run to be %s. Got %s", expected, got)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"30 0 * *",
+		"30 0 * * * *",
+		"60 0 * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every5m",
+	} {
+		if _, err := cron.Parse(expr); err == nil {
+			t.Errorf("Expected Parse(%q) to fail", expr)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	testCases := []struct {
+		expr        string
+		field       string
+		pos         int
+		expectedErr string
+	}{
+		{
+			expr:        "30 0 * *",
+			expectedErr: `cron "30 0 * *": expected 5 fields (minute hour day-of-month month day-of-week), got 4`,
+		},
+		{
+			expr:        "60 0 * * *",
+			field:       "minute",
+			pos:         1,
+			expectedErr: `cron "60 0 * * *": minute field at position 1: value 60 out of range, allowed values are 0-59`,
+		},
+		{
+			expr:        "0  25 * * *",
+			field:       "hour",
+			pos:         4,
+			expectedErr: `cron "0  25 * * *": hour field at position 4: value 25 out of range, allowed values are 0-23`,
+		},
+		{
+			expr:        "0 0 1,15,32 * *",
+			field:       "day of month",
+			pos:         10,
+			expectedErr: `cron "0 0 1,15,32 * *": day of month field at position 10: value 32 out of range, allowed values are 1-31`,
+		},
+		{
+			expr:        "0 0 * jan-foo *",
+			field:       "month",
+			pos:         11,
+			expectedErr: `cron "0 0 * jan-foo *": month field at position 11: invalid value "foo", allowed values are 1-12 or JAN-DEC`,
+		},
+		{
+			expr:        "0 0 * * */0",
+			field:       "day of week",
+			pos:         11,
+			expectedErr: `cron "0 0 * * */0": day of week field at position 11: invalid step "0", must be between 1 and 7`,
+		},
+		{
+			expr:        "@every5m",
+			expectedErr: `cron "@every5m": unknown descriptor "@every5m"`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.expr, func(t *testing.T) {
+			_, err := cron.Parse(tC.expr)
+
+			var perr *cron.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Expected a *cron.ParseError. Got %v", err)
+			}
+			if perr.Field != tC.field || perr.Pos != tC.pos {
+				t.Errorf("Expected field %q at %d. Got %q at %d", tC.field, tC.pos, perr.Field, perr.Pos)
+			}
+			if err.Error() != tC.expectedErr {
+				t.Errorf("Expected error to be\n%s\nGot\n%s", tC.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package lock provides named, exclusive locks so a job only runs on one instance at a time.
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Locker acquires named locks.
+type Locker interface {
+	// TryLock acquires the lock without waiting. ok is false if the lock is held elsewhere.
+	TryLock(ctx context.Context, name string) (l Lock, ok bool, err error)
+}
+
+// Lock is an acquired lock.
+type Lock interface {
+	// Lost is closed when the lock can no longer be guaranteed, e.g. the connection that holds it dropped.
+	// The work done under the lock must stop once it is closed.
+	Lost() <-chan struct{}
+	// Claim records tick, e.g. the scheduled time of a run, as done under the lock. ok is false if
+	// the tick or a later one was already claimed, by this or another holder of the lock.
+	// Claims outlive the lock, so a replica that fires the same tick late doesn't run it again.
+	Claim(ctx context.Context, tick time.Time) (ok bool, err error)
+	// Unlock releases the lock.
+	Unlock(ctx context.Context) error
+}
+
+// Memory is an in-memory Locker for single instance setups.
+type Memory struct {
+	mu     sync.Mutex
+	held   map[string]bool
+	claims map[string]time.Time // last claimed tick of every lock
+}
+
+// NewMemory initiates a new in-memory locker.
+func NewMemory() *Memory {
+	return &Memory{held: map[string]bool{}, claims: map[string]time.Time{}}
+}
+
+// TryLock acquires the lock named name if it is not already held.
+func (m *Memory) TryLock(ctx context.Context, name string) (Lock, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.held[name] {
+		return nil, false, nil
+	}
+	m.held[name] = true
+	return &memoryLock{m: m, name: name, lost: make(chan struct{})}, true, nil
+}
+
+type memoryLock struct {
+	m    *Memory
+	name string
+	lost chan struct{}
+	once sync.Once
+}
+
+// Lost is never closed for an in-memory lock.
+func (l *memoryLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *memoryLock) Claim(ctx context.Context, tick time.Time) (bool, error) {
+	l.m.mu.Lock()
+	defer l.m.mu.Unlock()
+
+	if last, ok := l.m.claims[l.name]; ok && !tick.After(last) {
+		return false, nil
+	}
+	l.m.claims[l.name] = tick
+	return true, nil
+}
+
+func (l *memoryLock) Unlock(ctx context.Context) error {
+	l.once.Do(func() {
+		l.m.mu.Lock()
+		defer l.m.mu.Unlock()
+		delete(l.m.held, l.name)
+	})
+	return nil
+}
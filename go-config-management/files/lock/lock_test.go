@@ -0,0 +1,120 @@
+package lock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"code.com/lock"
+	"code.com/test"
+)
+
+func testLocker(t *testing.T, l lock.Locker) {
+	t.Helper()
+	ctx := context.Background()
+
+	first, ok, err := l.TryLock(ctx, "inventoryCron")
+	if err != nil || !ok {
+		t.Fatalf("Expected the first TryLock to succeed. ok: %v, err: %v", ok, err)
+	}
+
+	if _, ok, err := l.TryLock(ctx, "inventoryCron"); err != nil || ok {
+		t.Fatalf("Expected the second TryLock to fail. ok: %v, err: %v", ok, err)
+	}
+
+	other, ok, err := l.TryLock(ctx, "invoicesCron")
+	if err != nil || !ok {
+		t.Fatalf("Expected a different lock to be acquired. ok: %v, err: %v", ok, err)
+	}
+	defer other.Unlock(ctx)
+
+	tick := time.Date(2022, 5, 23, 0, 30, 0, 0, time.UTC)
+	if ok, err := first.Claim(ctx, tick); err != nil || !ok {
+		t.Fatalf("Expected the first Claim to succeed. ok: %v, err: %v", ok, err)
+	}
+	if ok, err := other.Claim(ctx, tick); err != nil || !ok {
+		t.Fatalf("Expected the tick of a different lock to be claimed. ok: %v, err: %v", ok, err)
+	}
+
+	if err := first.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	again, ok, err := l.TryLock(ctx, "inventoryCron")
+	if err != nil || !ok {
+		t.Fatalf("Expected TryLock to succeed after Unlock. ok: %v, err: %v", ok, err)
+	}
+	// claims outlive the lock.
+	for _, claimed := range []time.Time{tick, tick.Add(-time.Minute)} {
+		if ok, err := again.Claim(ctx, claimed); err != nil || ok {
+			t.Errorf("Expected the claim of %s to fail. ok: %v, err: %v", claimed, ok, err)
+		}
+	}
+	if ok, err := again.Claim(ctx, tick.Add(time.Minute)); err != nil || !ok {
+		t.Errorf("Expected a later tick to be claimed. ok: %v, err: %v", ok, err)
+	}
+	if err := again.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testLocker(t, lock.NewMemory())
+}
+
+func TestPostgres(t *testing.T) {
+	db := test.SetupDB(t)
+
+	l := lock.NewPostgres(db, time.Second)
+	if err := l.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	testLocker(t, l)
+}
+
+func TestPostgres_SharedAcrossReplicas(t *testing.T) {
+	db := test.SetupDB(t)
+	replica := test.SetupDB(t)
+	ctx := context.Background()
+
+	l, ok, err := lock.NewPostgres(db, time.Second).TryLock(ctx, "inventoryCron")
+	if err != nil || !ok {
+		t.Fatalf("Expected TryLock to succeed. ok: %v, err: %v", ok, err)
+	}
+	defer l.Unlock(ctx)
+
+	if _, ok, err := lock.NewPostgres(replica, time.Second).TryLock(ctx, "inventoryCron"); err != nil || ok {
+		t.Fatalf("Expected the replica to not acquire the lock. ok: %v, err: %v", ok, err)
+	}
+}
+
+func TestPostgres_Lost(t *testing.T) {
+	db := test.SetupDB(t)
+	ctx := context.Background()
+
+	l, ok, err := lock.NewPostgres(db, 10*time.Millisecond).TryLock(ctx, "inventoryCron")
+	if err != nil || !ok {
+		t.Fatalf("Expected TryLock to succeed. ok: %v, err: %v", ok, err)
+	}
+
+	// kill the session that holds the lock.
+	key := lock.Key("inventoryCron")
+	_, err = db.Exec(`
+		SELECT pg_terminate_backend(pid) FROM pg_locks
+		WHERE locktype = 'advisory' AND classid::bigint = $1 AND objid::bigint = $2`,
+		int64(uint32(uint64(key)>>32)), int64(uint32(key)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-l.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the lock to be lost")
+	}
+
+	if err := l.Unlock(ctx); err != nil {
+		t.Errorf("Expected Unlock of a lost lock to succeed. Got %v", err)
+	}
+}
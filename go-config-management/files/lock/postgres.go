@@ -0,0 +1,179 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+)
+
+// Postgres is a Locker backed by session level Postgres advisory locks, so a lock is
+// held by every replica that shares the database only once.
+//
+// Each lock pins a connection of the pool for as long as it is held. The lease is renewed
+// by checking that the connection is alive and still holds the lock every renewInterval.
+// When the renewal fails the lock is considered lost.
+type Postgres struct {
+	db            *sql.DB
+	renewInterval time.Duration
+}
+
+// NewPostgres initiates a new Postgres locker. Migrate must be called before the locks are claimed.
+func NewPostgres(db *sql.DB, renewInterval time.Duration) *Postgres {
+	return &Postgres{db: db, renewInterval: renewInterval}
+}
+
+// Migrate creates the lock_claims table, which holds the last claimed tick of every lock.
+func (p *Postgres) Migrate(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS lock_claims(
+			name text PRIMARY KEY,
+			tick timestamptz NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create the lock_claims table. %v", err)
+	}
+	return nil
+}
+
+// Key returns the advisory lock key of the lock named name.
+func Key(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// TryLock acquires the advisory lock of name without waiting.
+func (p *Postgres) TryLock(ctx context.Context, name string) (Lock, bool, error) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get a connection. %v", err)
+	}
+
+	key := Key(name)
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire lock %s. %v", name, err)
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	l := &pgLock{
+		conn: conn,
+		name: name,
+		key:  key,
+		lost: make(chan struct{}),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go l.renew(p.renewInterval)
+	return l, true, nil
+}
+
+type pgLock struct {
+	conn *sql.Conn
+	name string
+	key  int64
+
+	lost     chan struct{}
+	lostOnce sync.Once
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func (l *pgLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *pgLock) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+func (l *pgLock) renew(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		held, err := l.held(ctx)
+		cancel()
+		if err != nil || !held {
+			log.Printf("lost lock %s. held: %v, err: %v", l.name, held, err)
+			l.markLost()
+			return
+		}
+	}
+}
+
+// held checks whether the session of the connection still holds the lock.
+// A bigint advisory lock key is split into classid (high 32 bits) and objid (low 32 bits) in pg_locks.
+func (l *pgLock) held(ctx context.Context) (bool, error) {
+	var held bool
+	err := l.conn.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory'
+				AND pid = pg_backend_pid()
+				AND granted
+				AND objsubid = 1
+				AND classid::bigint = $1
+				AND objid::bigint = $2
+		)`, int64(uint32(uint64(l.key)>>32)), int64(uint32(l.key)),
+	).Scan(&held)
+	return held, err
+}
+
+// Claim stores tick as the last claimed tick of the lock, unless the stored one is the same or later.
+func (l *pgLock) Claim(ctx context.Context, tick time.Time) (bool, error) {
+	res, err := l.conn.ExecContext(ctx, `
+		INSERT INTO lock_claims(name, tick) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET tick = EXCLUDED.tick
+		WHERE lock_claims.tick < EXCLUDED.tick`, l.name, tick,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim lock %s. %v", l.name, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim lock %s. %v", l.name, err)
+	}
+	return n == 1, nil
+}
+
+// Unlock releases the lock and returns the connection to the pool.
+func (l *pgLock) Unlock(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+	defer l.conn.Close()
+
+	select {
+	case <-l.lost:
+		// the session is gone, and the lock with it.
+		return nil
+	default:
+	}
+
+	var ok bool
+	if err := l.conn.QueryRowContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key).Scan(&ok); err != nil {
+		return fmt.Errorf("failed to release lock %s. %v", l.name, err)
+	}
+	if !ok {
+		return fmt.Errorf("lock %s was not held", l.name)
+	}
+	return nil
+}
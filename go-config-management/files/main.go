@@ -1,10 +1,216 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"time"
+
+	"code.com/admin"
 	"code.com/config"
+	"code.com/lock"
+	"code.com/notify"
+	"code.com/scheduler"
+
+	// Postgres driver
+	_ "github.com/jackc/pgx/v4/stdlib"
 )
 
+// Usage:
+//
+//	files [-cron-config-file=path]                     runs the scheduler
+//	files [-cron-config-file=path] next [-n=5]         prints the next fire times of every cron
+//	files [-cron-config-file=path] [-env=prod] config  prints the cron config merged with the overlay of env
+//	files schema                                       prints the JSON Schema of cron config files
 func main() {
 	c, err := config.Parse()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var cmd string
+	if len(c.Args) > 0 {
+		cmd = c.Args[0]
+	}
+	switch cmd {
+	case "":
+		runScheduler(c)
+	case "next":
+		if err := printNextRuns(os.Stdout, c, c.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+	case "config":
+		if err := printConfig(os.Stdout, c); err != nil {
+			log.Fatal(err)
+		}
+	case "schema":
+		if err := printSchema(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown command %q", cmd)
+	}
+}
+
+func runScheduler(c config.Config) {
+	if c.AdminAddr != "" && c.AdminToken == "" {
+		log.Fatal("-admin-token is required to serve the admin routes")
+	}
+
+	// every job in the cron config file needs a function here, and the other way around.
+	jobs := scheduler.Registry{
+		"inventoryCron": calculateInventoryStats,
+		"invoicesCron":  generateInvoices,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	d := notify.NewDispatcher(notify.Retry(notifier(c), 5, time.Second))
+	dispatched := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(dispatched)
+	}()
+
+	locker, err := locker(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cc := c.CronConfigs
+	var w *config.Watcher
+	if c.CronConfigReload > 0 {
+		if w, err = config.NewWatcher(c.CronConfigFile, c.Env, os.LookupEnv, c.CronConfigReload); err != nil {
+			log.Fatal(err)
+		}
+		// the file may have changed since it was parsed.
+		cc = w.Current()
+	}
+
+	s := scheduler.New(scheduler.WithRunHook(d.Observe), scheduler.WithLocker(locker))
+	if err := jobs.Register(s, cc); err != nil {
+		log.Fatal(err)
+	}
+
+	s.Start(ctx)
+	if c.AdminAddr != "" {
+		routes := map[string]http.Handler{"/jobs": s.Handler()}
+		if w != nil {
+			routes["/config/history"] = w.History().Handler()
+		}
+		go serveAdmin(ctx, c.AdminAddr, admin.Handler(c.AdminToken.Value(), routes))
+	}
+	if w != nil {
+		w.Subscribe(func(old, new config.CronConfigs) {
+			updateJobs(s, jobs, old, new)
+		})
+		go w.Run(ctx)
+	}
+
+	<-ctx.Done()
+	s.Wait()
+	<-dispatched
+
+	// send the runs that finished after the dispatcher's last flush.
+	if err := d.Flush(context.Background()); err != nil {
+		log.Printf("failed to send notifications. %v", err)
+	}
+}
+
+// serveAdmin serves the admin routes on addr until ctx is done.
+func serveAdmin(ctx context.Context, addr string, h http.Handler) {
+	srv := &http.Server{
+		Addr:         addr,
+		Handler:      h,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("serving the admin routes on %s", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("failed to serve the admin routes. %v", err)
+	}
+}
+
+// updateJobs reschedules the jobs whose config changed after the config file was reloaded.
+// Jobs removed from the file are disabled. Jobs added to it can't be scheduled until
+// their function is added to the registry.
+func updateJobs(s *scheduler.Scheduler, jobs scheduler.Registry, old, new config.CronConfigs) {
+	if err := jobs.Check(new); err != nil {
+		log.Printf("the reloaded cron config doesn't match the registered jobs. %v", err)
+	}
+
+	for name := range jobs {
+		conf, ok := new[name]
+		if !ok {
+			if conf, ok = old[name]; !ok {
+				continue
+			}
+			conf.Disabled = true
+		}
+		if reflect.DeepEqual(old[name], conf) {
+			continue
+		}
+		if err := s.Update(name, conf); err != nil {
+			log.Printf("failed to update job %s. %v", name, err)
+			continue
+		}
+		log.Printf("job %s was updated", name)
+	}
+}
+
+// locker returns a Postgres locker when a database is configured, so replicas run each job once.
+func locker(c config.Config) (lock.Locker, error) {
+	if c.DatabaseURL == "" {
+		return lock.NewMemory(), nil
+	}
+
+	db, err := sql.Open("pgx", c.DatabaseURL.Value())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database. %v", err)
+	}
+	l := lock.NewPostgres(db, 10*time.Second)
+	if err := l.Migrate(context.Background()); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// notifier picks the job notification channel from the config. Notifications are logged by default.
+func notifier(c config.Config) notify.Notifier {
+	switch {
+	case c.NotifyWebhook != "":
+		return notify.Webhook{URL: c.NotifyWebhook}
+	case c.SMTPAddr != "":
+		return notify.SMTP{Addr: c.SMTPAddr, From: c.SMTPFrom}
+	default:
+		return notify.Log{}
+	}
+}
+
+func calculateInventoryStats(ctx context.Context, args map[string]string) error {
+	// ...
+	return nil
+}
+
+func generateInvoices(ctx context.Context, args map[string]string) error {
 	// ...
+	return nil
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"code.com/config"
+	"code.com/cron"
+)
+
+// printNextRuns prints the next fire times of every configured cron so a config can be checked before deploying.
+func printNextRuns(w io.Writer, c config.Config, args []string) error {
+	fs := flag.NewFlagSet("next", flag.ContinueOnError)
+	n := fs.Int("n", 5, "number of fire times to print per cron.")
+	from := fs.String("from", "", "RFC3339 time to start from. Defaults to now.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if *from != "" {
+		var err error
+		if start, err = time.Parse(time.RFC3339, *from); err != nil {
+			return fmt.Errorf("invalid -from. %v", err)
+		}
+	}
+
+	for _, name := range c.CronConfigs.Names() {
+		conf := c.CronConfigs[name]
+		// config.Parse already validated the schedules and time zones.
+		schedule, err := cron.Parse(conf.Schedule)
+		if err != nil {
+			return err
+		}
+		loc := time.Local
+		if conf.TimeZone != "" {
+			if loc, err = time.LoadLocation(conf.TimeZone); err != nil {
+				return err
+			}
+		}
+
+		status := ""
+		if conf.Disabled {
+			status = " (disabled)"
+		}
+		fmt.Fprintf(w, "%s %q%s\n", name, conf.Schedule, status)
+
+		t := start.In(loc)
+		for i := 0; i < *n; i++ {
+			if t = schedule.Next(t); t.IsZero() {
+				fmt.Fprintln(w, "  never")
+				break
+			}
+			fmt.Fprintf(w, "  %s\n", t.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"code.com/config"
+	"code.com/scheduler"
+)
+
+// Summary is the data the subject and body templates are executed with.
+// Skipped runs count as failed.
+type Summary struct {
+	Runs      []scheduler.Run
+	Failed    []scheduler.Run
+	Succeeded []scheduler.Run
+}
+
+// Default templates of the notifications.
+var (
+	DefaultSubject = template.Must(template.New("subject").Parse(
+		`{{if .Failed}}{{len .Failed}} of {{len .Runs}} scheduled job run(s) failed` +
+			`{{else}}{{len .Runs}} scheduled job run(s) succeeded{{end}}`,
+	))
+	DefaultBody = template.Must(template.New("body").Parse(
+		`{{range .Runs}}{{.Job}} scheduled at {{.ScheduledAt.Format "2006-01-02T15:04:05Z07:00"}}: ` +
+			`{{if .Skipped}}skipped, the previous run was still in progress` +
+			`{{else if .Err}}failed after {{.FinishedAt.Sub .StartedAt}}. {{.Err}}` +
+			`{{else}}succeeded in {{.FinishedAt.Sub .StartedAt}}{{end}}
+{{end}}`,
+	))
+)
+
+// Dispatcher batches the runs of the jobs per NotifyEmail recipients and sends
+// one summary per batch. Use Observe as a scheduler.RunHook.
+type Dispatcher struct {
+	notifier  Notifier
+	subject   *template.Template
+	body      *template.Template
+	onSuccess bool
+	window    time.Duration
+	maxBatch  int
+
+	mu      sync.Mutex
+	pending map[string]*batch
+	full    chan struct{}
+}
+
+type batch struct {
+	to   []string
+	runs []scheduler.Run
+}
+
+// Option configures a Dispatcher.
+type Option func(*Dispatcher)
+
+// WithTemplates sets the subject and body templates. Nil templates keep the defaults.
+func WithTemplates(subject, body *template.Template) Option {
+	return func(d *Dispatcher) {
+		if subject != nil {
+			d.subject = subject
+		}
+		if body != nil {
+			d.body = body
+		}
+	}
+}
+
+// WithSuccess makes the dispatcher notify about successful runs too. Only failures are sent by default.
+func WithSuccess() Option {
+	return func(d *Dispatcher) { d.onSuccess = true }
+}
+
+// WithWindow sets how long runs are batched before they are sent. Defaults to 1 minute.
+func WithWindow(window time.Duration) Option {
+	return func(d *Dispatcher) { d.window = window }
+}
+
+// WithMaxBatch sets the number of runs that triggers sending a batch before the window ends. Defaults to 20.
+func WithMaxBatch(n int) Option {
+	return func(d *Dispatcher) { d.maxBatch = n }
+}
+
+// NewDispatcher initiates a new dispatcher that sends the summaries through n.
+func NewDispatcher(n Notifier, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		notifier: n,
+		subject:  DefaultSubject,
+		body:     DefaultBody,
+		window:   time.Minute,
+		maxBatch: 20,
+		pending:  map[string]*batch{},
+		full:     make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Observe queues run to be sent to the NotifyEmail recipients of the job.
+func (d *Dispatcher) Observe(run scheduler.Run, conf config.CronConfig) {
+	failed := run.Err != "" || run.Skipped
+	if len(conf.NotifyEmail) == 0 || (!failed && !d.onSuccess) {
+		return
+	}
+
+	to := append([]string(nil), conf.NotifyEmail...)
+	sort.Strings(to)
+	key := strings.Join(to, ",")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b, ok := d.pending[key]
+	if !ok {
+		b = &batch{to: to}
+		d.pending[key] = b
+	}
+	b.runs = append(b.runs, run)
+
+	if len(b.runs) >= d.maxBatch {
+		select {
+		case d.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run sends the pending batches every window, or earlier when a batch is full, until ctx is done.
+// The remaining batches are sent before it returns.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := d.Flush(flushCtx); err != nil {
+				log.Printf("failed to send notifications. %v", err)
+			}
+			return
+		case <-ticker.C:
+		case <-d.full:
+		}
+
+		if err := d.Flush(ctx); err != nil {
+			log.Printf("failed to send notifications. %v", err)
+		}
+	}
+}
+
+// Flush sends all the pending batches. Batches that fail to send are dropped.
+func (d *Dispatcher) Flush(ctx context.Context) error {
+	d.mu.Lock()
+	pending := d.pending
+	d.pending = map[string]*batch{}
+	d.mu.Unlock()
+
+	keys := make([]string, 0, len(pending))
+	for k := range pending {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []string
+	for _, k := range keys {
+		b := pending[k]
+		m, err := d.render(b)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := d.notifier.Notify(ctx, m); err != nil {
+			errs = append(errs, fmt.Sprintf("to %s: %v", k, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d notification(s) failed. %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (d *Dispatcher) render(b *batch) (Message, error) {
+	s := Summary{Runs: b.runs}
+	for _, r := range b.runs {
+		if r.Err != "" || r.Skipped {
+			s.Failed = append(s.Failed, r)
+		} else {
+			s.Succeeded = append(s.Succeeded, r)
+		}
+	}
+
+	var subject, body strings.Builder
+	if err := d.subject.Execute(&subject, s); err != nil {
+		return Message{}, fmt.Errorf("failed to render subject. %v", err)
+	}
+	if err := d.body.Execute(&body, s); err != nil {
+		return Message{}, fmt.Errorf("failed to render body. %v", err)
+	}
+
+	return Message{To: b.to, Subject: subject.String(), Body: body.String()}, nil
+}
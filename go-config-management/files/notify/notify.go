@@ -0,0 +1,101 @@
+// Package notify sends summaries of scheduled job runs to the NotifyEmail recipients of the jobs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Message is a rendered notification.
+type Message struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// Log is a Notifier that only logs the messages.
+type Log struct{}
+
+// Notify logs m.
+func (Log) Notify(ctx context.Context, m Message) error {
+	log.Printf("notification to %s: %s\n%s", strings.Join(m.To, ", "), m.Subject, m.Body)
+	return nil
+}
+
+// Webhook is a Notifier that posts the messages as JSON to a URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client // Defaults to a client with a 10 second timeout
+}
+
+// Notify posts m to the webhook URL. Any non 2xx response is an error.
+func (wh Webhook) Notify(ctx context.Context, m Message) error {
+	bb, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message. %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", wh.URL, bytes.NewReader(bb))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request. %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := wh.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook. %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Retry wraps n so that failed notifications are retried up to attempts times in total,
+// waiting backoff before the first retry and doubling it after every failure.
+func Retry(n Notifier, attempts int, backoff time.Duration) Notifier {
+	return retry{next: n, attempts: attempts, backoff: backoff}
+}
+
+type retry struct {
+	next     Notifier
+	attempts int
+	backoff  time.Duration
+}
+
+func (r retry) Notify(ctx context.Context, m Message) error {
+	var err error
+	wait := r.backoff
+	for i := 0; i < r.attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("gave up retrying. %v", err)
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+
+		if err = r.next.Notify(ctx, m); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed after %d attempts. %v", r.attempts, err)
+}
@@ -0,0 +1,197 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"code.com/config"
+	"code.com/notify"
+	"code.com/scheduler"
+	"code.com/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+var scheduledAt = time.Date(2022, 5, 23, 0, 30, 0, 0, time.UTC)
+
+func run(job string, err string) scheduler.Run {
+	return scheduler.Run{
+		Job:         job,
+		ScheduledAt: scheduledAt,
+		StartedAt:   scheduledAt,
+		FinishedAt:  scheduledAt.Add(2 * time.Second),
+		Err:         err,
+	}
+}
+
+func TestDispatcher_SMTP(t *testing.T) {
+	srv := test.NewSMTPServer(t)
+	d := notify.NewDispatcher(notify.SMTP{Addr: srv.Addr, From: "cron@code.com"})
+
+	inventory := config.CronConfig{NotifyEmail: []string{"jdoe@gmail.com", "ops@code.com"}}
+	invoices := config.CronConfig{NotifyEmail: []string{"ops@code.com", "jdoe@gmail.com"}}
+	silent := config.CronConfig{}
+
+	d.Observe(run("inventoryCron", "db is down"), inventory)
+	d.Observe(run("inventoryCron", ""), inventory) // successes are not sent by default
+	d.Observe(scheduler.Run{Job: "invoicesCron", ScheduledAt: scheduledAt, Skipped: true}, invoices)
+	d.Observe(run("otherCron", "boom"), silent) // no recipients
+
+	if err := d.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mm := srv.Messages()
+	if len(mm) != 1 {
+		t.Fatalf("Expected the runs to be batched into 1 message. Got %d", len(mm))
+	}
+
+	if diff := cmp.Diff([]string{"jdoe@gmail.com", "ops@code.com"}, mm[0].To); diff != "" {
+		t.Errorf("recipients are different (-want +got):\n%s", diff)
+	}
+	for _, want := range []string{
+		"Subject: 2 of 2 scheduled job run(s) failed",
+		"inventoryCron scheduled at 2022-05-23T00:30:00Z: failed after 2s. db is down",
+		"invoicesCron scheduled at 2022-05-23T00:30:00Z: skipped, the previous run was still in progress",
+	} {
+		if !strings.Contains(mm[0].Data, want) {
+			t.Errorf("Expected message to contain %q. Got\n%s", want, mm[0].Data)
+		}
+	}
+
+	// pending batches are cleared after a flush.
+	if err := d.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.Messages()) != 1 {
+		t.Errorf("Expected no new messages after the second flush")
+	}
+}
+
+func TestDispatcher_SuccessAndTemplates(t *testing.T) {
+	srv := test.NewSMTPServer(t)
+	d := notify.NewDispatcher(
+		notify.SMTP{Addr: srv.Addr, From: "cron@code.com"},
+		notify.WithSuccess(),
+		notify.WithTemplates(
+			template.Must(template.New("s").Parse(`{{len .Succeeded}} ok`)),
+			template.Must(template.New("b").Parse(`{{range .Runs}}{{.Job}}{{end}}`)),
+		),
+	)
+
+	d.Observe(run("inventoryCron", ""), config.CronConfig{NotifyEmail: []string{"jdoe@gmail.com"}})
+	if err := d.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mm := srv.Messages()
+	if len(mm) != 1 || !strings.Contains(mm[0].Data, "Subject: 1 ok") || !strings.Contains(mm[0].Data, "\r\n\r\ninventoryCron") {
+		t.Errorf("unexpected messages %+v", mm)
+	}
+}
+
+func TestDispatcher_RunSendsFullBatches(t *testing.T) {
+	srv := test.NewSMTPServer(t)
+	d := notify.NewDispatcher(
+		notify.SMTP{Addr: srv.Addr, From: "cron@code.com"},
+		notify.WithWindow(time.Hour),
+		notify.WithMaxBatch(2),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	conf := config.CronConfig{NotifyEmail: []string{"jdoe@gmail.com"}}
+	d.Observe(run("inventoryCron", "boom"), conf)
+	d.Observe(run("inventoryCron", "boom"), conf)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.Messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(srv.Messages()) != 1 {
+		t.Fatalf("Expected the full batch to be sent before the window ends")
+	}
+
+	// the remaining runs are sent on shutdown.
+	d.Observe(run("inventoryCron", "boom"), conf)
+	cancel()
+	<-done
+	if len(srv.Messages()) != 2 {
+		t.Errorf("Expected the pending batch to be sent on shutdown. Got %d messages", len(srv.Messages()))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	srv := test.NewSMTPServer(t)
+	srv.FailNext(2)
+
+	n := notify.Retry(notify.SMTP{Addr: srv.Addr, From: "cron@code.com"}, 3, time.Millisecond)
+	if err := n.Notify(context.Background(), notify.Message{To: []string{"jdoe@gmail.com"}, Subject: "hi"}); err != nil {
+		t.Fatalf("Expected the third attempt to succeed. Got %v", err)
+	}
+	if len(srv.Messages()) != 1 {
+		t.Errorf("Expected 1 message. Got %d", len(srv.Messages()))
+	}
+
+	srv.FailNext(3)
+	if err := n.Notify(context.Background(), notify.Message{To: []string{"jdoe@gmail.com"}}); err == nil {
+		t.Error("Expected Notify to fail after 3 attempts")
+	}
+}
+
+type notifierFunc func(ctx context.Context, m notify.Message) error
+
+func (f notifierFunc) Notify(ctx context.Context, m notify.Message) error { return f(ctx, m) }
+
+func TestRetry_StopsOnContextDone(t *testing.T) {
+	calls := 0
+	n := notify.Retry(notifierFunc(func(ctx context.Context, m notify.Message) error {
+		calls++
+		return errors.New("boom")
+	}), 5, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := n.Notify(ctx, notify.Message{}); err == nil {
+		t.Error("Expected Notify to fail")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call. Got %d", calls)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got notify.Message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	m := notify.Message{To: []string{"jdoe@gmail.com"}, Subject: "subject", Body: "body"}
+	if err := (notify.Webhook{URL: srv.URL}).Notify(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(m, got); diff != "" {
+		t.Errorf("messages are different (-want +got):\n%s", diff)
+	}
+
+	if err := (notify.Webhook{URL: srv.URL + "/fail"}).Notify(context.Background(), m); err == nil {
+		t.Error("Expected a non 2xx response to fail")
+	}
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP is a Notifier that sends the messages as plain text emails.
+type SMTP struct {
+	Addr string    // host:port of the SMTP server
+	From string    // Sender address
+	Auth smtp.Auth // Optional. smtp.PlainAuth only works over TLS or on localhost
+}
+
+// Notify sends m to its recipients.
+func (s SMTP) Notify(ctx context.Context, m Message) error {
+	if len(m.To) == 0 {
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.Addr, s.Auth, s.From, m.To, s.build(m))
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email. %v", err)
+		}
+		return nil
+	}
+}
+
+func (s SMTP) build(m Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.To, ", "))
+	// a subject with line breaks could inject headers.
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
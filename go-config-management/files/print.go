@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"code.com/config"
+)
+
+// printedCronConfig prints the timeout as a duration string like the config files have it, not in nanoseconds.
+type printedCronConfig struct {
+	config.CronConfig
+	Timeout string `json:"timeout"`
+}
+
+// printConfig prints the cron config after the overlay of the environment was merged into it,
+// so the configs of two environments can be diffed.
+func printConfig(w io.Writer, c config.Config) error {
+	cc := make(map[string]printedCronConfig, len(c.CronConfigs))
+	for name, conf := range c.CronConfigs {
+		cc[name] = printedCronConfig{CronConfig: conf, Timeout: conf.Timeout.String()}
+	}
+	bb, err := json.MarshalIndent(cc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cron config. %v", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", bb)
+	return err
+}
+
+// printSchema prints the JSON Schema of cron config files, see config.Schema.
+func printSchema(w io.Writer) error {
+	bb, err := json.MarshalIndent(config.Schema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema. %v", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", bb)
+	return err
+}
@@ -0,0 +1,24 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler serves the registered jobs and their run history as JSON.
+func (s *Scheduler) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := struct {
+			Jobs    []JobStatus `json:"jobs"`
+			History []Run       `json:"history"`
+		}{
+			Jobs:    s.Jobs(),
+			History: s.History(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.com/config"
+)
+
+// Registry maps job names to their functions.
+type Registry map[string]Job
+
+// Check matches the configured jobs to the registered functions. The returned error lists every
+// job that is configured without a function and every function without a config, disabled jobs included,
+// so a typo in either place doesn't silently leave a job unscheduled.
+func (r Registry) Check(cc config.CronConfigs) error {
+	var errs []string
+	for _, name := range cc.Names() {
+		if _, ok := r[name]; !ok {
+			errs = append(errs, fmt.Sprintf("job %s is configured but has no function", name))
+		}
+	}
+
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := cc[name]; !ok {
+			errs = append(errs, fmt.Sprintf("job %s has a function but no config", name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d orphaned job(s):\n\t%s", len(errs), strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
+
+// Register checks the configured jobs against the registry and registers them all on s with their functions.
+func (r Registry) Register(s *Scheduler, cc config.CronConfigs) error {
+	if err := r.Check(cc); err != nil {
+		return err
+	}
+	for _, name := range cc.Names() {
+		if err := s.Register(name, cc[name], r[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
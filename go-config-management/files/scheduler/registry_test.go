@@ -0,0 +1,64 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+
+	"code.com/config"
+	"code.com/scheduler"
+)
+
+func noop(ctx context.Context, args map[string]string) error { return nil }
+
+func TestRegistry_Register(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		registry    scheduler.Registry
+		configs     config.CronConfigs
+		expectedErr string
+	}{
+		{
+			desc:     "matching",
+			registry: scheduler.Registry{"inventoryCron": noop, "invoicesCron": noop},
+			configs: config.CronConfigs{
+				"inventoryCron": {Schedule: "30 0 * * *"},
+				"invoicesCron":  {Schedule: "10 0 * * *", Disabled: true},
+			},
+		},
+		{
+			desc:     "orphans in both directions",
+			registry: scheduler.Registry{"inventoryCron": noop, "invoicesCron": noop},
+			configs: config.CronConfigs{
+				"inventoryCron": {Schedule: "30 0 * * *"},
+				"reportsCron":   {Schedule: "0 6 * * 1"},
+			},
+			expectedErr: "2 orphaned job(s):\n" +
+				"\tjob reportsCron is configured but has no function\n" +
+				"\tjob invoicesCron has a function but no config",
+		},
+		{
+			desc:        "invalid schedule",
+			registry:    scheduler.Registry{"inventoryCron": noop},
+			configs:     config.CronConfigs{"inventoryCron": {Schedule: "30 0 * *"}},
+			expectedErr: `invalid schedule for job inventoryCron. cron "30 0 * *": expected 5 fields (minute hour day-of-month month day-of-week), got 4`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := scheduler.New()
+			err := tC.registry.Register(s, tC.configs)
+			if tC.expectedErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if jj := s.Jobs(); len(jj) != len(tC.configs) {
+					t.Errorf("Expected every job to be registered. Got %+v", jj)
+				}
+				return
+			}
+			if err == nil || err.Error() != tC.expectedErr {
+				t.Errorf("Expected error to be %q. Got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
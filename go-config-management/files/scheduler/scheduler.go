@@ -0,0 +1,442 @@
+// Package scheduler runs the jobs configured in config.CronConfigs on their cron schedules.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"code.com/config"
+	"code.com/cron"
+	"code.com/lock"
+)
+
+// Job is the function that runs on every tick of a schedule. args are the args of its config.
+type Job func(ctx context.Context, args map[string]string) error
+
+// Clock abstracts time so the scheduler can be driven by a fake clock in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Run is a single execution, or skipped execution, of a job.
+type Run struct {
+	Job         string    `json:"job"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt"`
+	Err         string    `json:"error,omitempty"`
+	Attempts    int       `json:"attempts,omitempty"` // 1 plus the number of retries
+	// Skipped is true when the previous run of the job was still in progress.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// RunHook is called after every recorded run with the config of the job.
+type RunHook func(run Run, conf config.CronConfig)
+
+type entry struct {
+	name     string
+	conf     config.CronConfig
+	schedule cron.Schedule
+	loc      *time.Location
+	fn       Job
+	running  bool
+	stop     context.CancelFunc // stops the loop of the job, nil if it isn't running
+}
+
+// Scheduler runs registered jobs on their schedules. Disabled jobs are registered but never run.
+type Scheduler struct {
+	clock        Clock
+	loc          *time.Location
+	jitter       time.Duration
+	historySize  int
+	retryBackoff time.Duration
+	hooks        []RunHook
+	locker       lock.Locker
+
+	mu      sync.Mutex
+	ctx     context.Context // passed to Start, nil before
+	rand    *rand.Rand
+	jobs    map[string]*entry
+	history []Run
+	wg      sync.WaitGroup
+}
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithClock sets the clock used by the scheduler. Defaults to the real clock.
+func WithClock(c Clock) Option {
+	return func(s *Scheduler) { s.clock = c }
+}
+
+// WithLocation sets the time zone of the jobs that don't set their own. Defaults to time.Local.
+func WithLocation(loc *time.Location) Option {
+	return func(s *Scheduler) { s.loc = loc }
+}
+
+// WithJitter delays every run by a random duration in [0, max) to spread load.
+func WithJitter(max time.Duration) Option {
+	return func(s *Scheduler) { s.jitter = max }
+}
+
+// WithRunHook adds a hook that is called after every run, including the skipped ones.
+func WithRunHook(h RunHook) Option {
+	return func(s *Scheduler) { s.hooks = append(s.hooks, h) }
+}
+
+// WithLocker makes every run hold the lock named after its job and claim its scheduled time
+// under it, so replicas sharing the locker run a job only once per tick, even when one of them
+// fires the tick after another one finished it, e.g. because of jitter or clock skew.
+// Runs are not locked by default.
+func WithLocker(l lock.Locker) Option {
+	return func(s *Scheduler) { s.locker = l }
+}
+
+// WithRetryBackoff sets how long to wait before retrying a failed run. The wait grows linearly
+// with every attempt. Defaults to a second.
+func WithRetryBackoff(d time.Duration) Option {
+	return func(s *Scheduler) { s.retryBackoff = d }
+}
+
+// WithHistorySize sets how many runs are kept in the history. Defaults to 100.
+func WithHistorySize(n int) Option {
+	return func(s *Scheduler) { s.historySize = n }
+}
+
+// New initiates a new scheduler.
+func New(opts ...Option) *Scheduler {
+	s := &Scheduler{
+		clock:        realClock{},
+		loc:          time.Local,
+		historySize:  100,
+		retryBackoff: time.Second,
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		jobs:         map[string]*entry{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register adds a job with its config. It must be called before Start.
+func (s *Scheduler) Register(name string, conf config.CronConfig, fn Job) error {
+	schedule, loc, err := s.parse(name, conf)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %s is already registered", name)
+	}
+	s.jobs[name] = &entry{name: name, conf: conf, schedule: schedule, loc: loc, fn: fn}
+	return nil
+}
+
+// Update replaces the config of a registered job, e.g. after the config file is reloaded.
+// The job is rescheduled right away. A run in progress isn't affected, even if the job is disabled.
+func (s *Scheduler) Update(name string, conf config.CronConfig) error {
+	schedule, loc, err := s.parse(name, conf)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("job %s is not registered", name)
+	}
+	if e.stop != nil {
+		e.stop()
+		e.stop = nil
+	}
+	e.conf, e.schedule, e.loc = conf, schedule, loc
+
+	if s.ctx == nil || s.ctx.Err() != nil {
+		return nil
+	}
+	if conf.Disabled {
+		log.Printf("job %s is disabled, stopping", name)
+		return nil
+	}
+	s.startLoop(e)
+	return nil
+}
+
+func (s *Scheduler) parse(name string, conf config.CronConfig) (cron.Schedule, *time.Location, error) {
+	schedule, err := cron.Parse(conf.Schedule)
+	if err != nil {
+		return cron.Schedule{}, nil, fmt.Errorf("invalid schedule for job %s. %v", name, err)
+	}
+
+	loc := s.loc
+	if conf.TimeZone != "" {
+		if loc, err = time.LoadLocation(conf.TimeZone); err != nil {
+			return cron.Schedule{}, nil, fmt.Errorf("invalid time zone for job %s. %v", name, err)
+		}
+	}
+	return schedule, loc, nil
+}
+
+// Start starts a goroutine for every enabled job. The jobs stop once ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ctx = ctx
+	for _, e := range s.jobs {
+		if e.conf.Disabled {
+			log.Printf("job %s is disabled, skipping", e.name)
+			continue
+		}
+		s.startLoop(e)
+	}
+}
+
+// startLoop starts the loop of a job. Must be called with s.mu held.
+func (s *Scheduler) startLoop(e *entry) {
+	ctx, stop := context.WithCancel(s.ctx)
+	e.stop = stop
+	s.wg.Add(1)
+	go s.loop(ctx, s.ctx, e)
+}
+
+// Wait blocks until all the job loops and in progress runs return after the context passed to Start is done.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// loop fires the job on its schedule until ctx is done. Runs get runCtx, so they aren't canceled
+// when the loop is stopped by Update.
+func (s *Scheduler) loop(ctx, runCtx context.Context, e *entry) {
+	defer s.wg.Done()
+
+	s.mu.Lock()
+	schedule, loc := e.schedule, e.loc
+	s.mu.Unlock()
+
+	for {
+		now := s.clock.Now()
+		next := schedule.Next(now.In(loc))
+		if next.IsZero() {
+			log.Printf("job %s has no upcoming runs, stopping", e.name)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(next.Sub(now) + s.randJitter()):
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.fire(runCtx, e, next)
+	}
+}
+
+func (s *Scheduler) randJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Duration(s.rand.Int63n(int64(s.jitter)))
+}
+
+// fire runs the job in its own goroutine unless the previous run is still in progress.
+func (s *Scheduler) fire(ctx context.Context, e *entry, scheduledAt time.Time) {
+	s.mu.Lock()
+	conf := e.conf
+	if e.running {
+		now := s.clock.Now()
+		run := Run{Job: e.name, ScheduledAt: scheduledAt, StartedAt: now, FinishedAt: now, Skipped: true}
+		s.record(run)
+		s.mu.Unlock()
+
+		log.Printf("job %s is still running, skipping the run scheduled at %s", e.name, scheduledAt)
+		s.runHooks(run, conf)
+		return
+	}
+	e.running = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		run := Run{Job: e.name, ScheduledAt: scheduledAt, StartedAt: s.clock.Now()}
+		attempts, locked, err := s.execute(ctx, e, conf, scheduledAt)
+		if locked {
+			s.mu.Lock()
+			e.running = false
+			s.mu.Unlock()
+			return
+		}
+		run.FinishedAt = s.clock.Now()
+		run.Attempts = attempts
+		if err != nil {
+			run.Err = err.Error()
+			log.Printf("job %s failed. %v", e.name, err)
+		}
+
+		s.mu.Lock()
+		e.running = false
+		s.record(run)
+		s.mu.Unlock()
+
+		s.runHooks(run, conf)
+	}()
+}
+
+// execute runs the job while holding its lock. locked is true if another instance holds the lock or
+// already claimed the tick scheduled at scheduledAt, and the job didn't run.
+// The job's context is canceled if the lock is lost while it is running.
+func (s *Scheduler) execute(
+	ctx context.Context, e *entry, conf config.CronConfig, scheduledAt time.Time,
+) (attempts int, locked bool, err error) {
+	if s.locker == nil {
+		attempts, err = s.retry(ctx, e, conf)
+		return attempts, false, err
+	}
+
+	l, ok, err := s.locker.TryLock(ctx, e.name)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to acquire the job lock. %v", err)
+	}
+	if !ok {
+		log.Printf("job %s is locked by another instance, skipping", e.name)
+		return 0, true, nil
+	}
+	defer func() {
+		if err := l.Unlock(context.Background()); err != nil {
+			log.Printf("failed to release the lock of job %s. %v", e.name, err)
+		}
+	}()
+
+	claimed, err := l.Claim(ctx, scheduledAt)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to claim the run. %v", err)
+	}
+	if !claimed {
+		log.Printf("job %s already ran at %s on another instance, skipping", e.name, scheduledAt)
+		return 0, true, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-l.Lost():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	attempts, err = s.retry(ctx, e, conf)
+	select {
+	case <-l.Lost():
+		return attempts, false, fmt.Errorf("the job lock was lost while running. %v", err)
+	default:
+		return attempts, false, err
+	}
+}
+
+// retry runs the job until it succeeds, up to 1 + conf.Retries times, each with conf.Timeout.
+func (s *Scheduler) retry(ctx context.Context, e *entry, conf config.CronConfig) (attempts int, err error) {
+	for attempts = 1; ; attempts++ {
+		if err = s.attempt(ctx, e, conf); err == nil || attempts > conf.Retries || ctx.Err() != nil {
+			return attempts, err
+		}
+
+		log.Printf("job %s failed, retrying. attempt: %d/%d, err: %v", e.name, attempts, conf.Retries+1, err)
+		select {
+		case <-ctx.Done():
+			return attempts, err
+		case <-s.clock.After(s.retryBackoff * time.Duration(attempts)):
+		}
+	}
+}
+
+func (s *Scheduler) attempt(ctx context.Context, e *entry, conf config.CronConfig) error {
+	if conf.Timeout <= 0 {
+		return e.fn(ctx, conf.Args)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, conf.Timeout)
+	defer cancel()
+	err := e.fn(ctx, conf.Args)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s. %v", conf.Timeout, err)
+	}
+	return err
+}
+
+func (s *Scheduler) runHooks(run Run, conf config.CronConfig) {
+	for _, h := range s.hooks {
+		h(run, conf)
+	}
+}
+
+// record appends a run to the history. Must be called with s.mu held.
+func (s *Scheduler) record(r Run) {
+	s.history = append(s.history, r)
+	if over := len(s.history) - s.historySize; over > 0 {
+		s.history = append(s.history[:0:0], s.history[over:]...)
+	}
+}
+
+// History returns the recorded runs, oldest first.
+func (s *Scheduler) History() []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rr := make([]Run, len(s.history))
+	copy(rr, s.history)
+	return rr
+}
+
+// JobStatus describes a registered job.
+type JobStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Disabled bool      `json:"disabled"`
+	Running  bool      `json:"running"`
+	NextRun  time.Time `json:"nextRun,omitempty"`
+}
+
+// Jobs returns the status of the registered jobs sorted by name.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	jj := make([]JobStatus, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		js := JobStatus{Name: e.name, Schedule: e.conf.Schedule, Disabled: e.conf.Disabled, Running: e.running}
+		if !e.conf.Disabled {
+			js.NextRun = e.schedule.Next(now.In(e.loc))
+		}
+		jj = append(jj, js)
+	}
+	sort.Slice(jj, func(i, j int) bool { return jj[i].Name < jj[j].Name })
+	return jj
+}
@@ -0,0 +1,508 @@
+package scheduler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"code.com/config"
+	"code.com/lock"
+	"code.com/scheduler"
+	"code.com/test"
+)
+
+var start = time.Date(2022, 5, 23, 0, 0, 0, 0, time.UTC)
+
+func startScheduler(t *testing.T, s *scheduler.Scheduler) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		s.Wait()
+	})
+}
+
+func waitForRuns(t *testing.T, s *scheduler.Scheduler, n int) []scheduler.Run {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if rr := s.History(); len(rr) >= n {
+			return rr
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d runs. got %v", n, s.History())
+	return nil
+}
+
+func TestScheduler_RunsJobOnSchedule(t *testing.T) {
+	clock := test.NewClock(start)
+	s := scheduler.New(scheduler.WithClock(clock), scheduler.WithLocation(time.UTC))
+
+	ran := make(chan struct{}, 10)
+	err := s.Register("inventoryCron", config.CronConfig{Schedule: "30 0 * * *"}, func(ctx context.Context, args map[string]string) error {
+		ran <- struct{}{}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+
+	clock.BlockUntil(t, 1)
+	clock.Advance(29 * time.Minute)
+	select {
+	case <-ran:
+		t.Fatal("job ran before its schedule")
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	rr := waitForRuns(t, s, 1)
+
+	expected := time.Date(2022, 5, 23, 0, 30, 0, 0, time.UTC)
+	if !rr[0].ScheduledAt.Equal(expected) {
+		t.Errorf("Expected run to be scheduled at %s. Got %s", expected, rr[0].ScheduledAt)
+	}
+	if rr[0].Job != "inventoryCron" || rr[0].Err != "" || rr[0].Skipped {
+		t.Errorf("unexpected run %+v", rr[0])
+	}
+}
+
+func TestScheduler_SkipsDisabledJobs(t *testing.T) {
+	clock := test.NewClock(start)
+	s := scheduler.New(scheduler.WithClock(clock), scheduler.WithLocation(time.UTC))
+
+	err := s.Register("invoicesCron", config.CronConfig{Schedule: "* * * * *", Disabled: true}, func(ctx context.Context, args map[string]string) error {
+		t.Error("disabled job ran")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Register("inventoryCron", config.CronConfig{Schedule: "* * * * *"}, func(ctx context.Context, args map[string]string) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+
+	// only the enabled job is waiting on the clock.
+	clock.BlockUntil(t, 1)
+	clock.Advance(time.Minute)
+	rr := waitForRuns(t, s, 1)
+	if rr[0].Job != "inventoryCron" {
+		t.Errorf("Expected inventoryCron to run. Got %s", rr[0].Job)
+	}
+
+	jj := s.Jobs()
+	if !jj[1].Disabled || !jj[1].NextRun.IsZero() {
+		t.Errorf("Expected invoicesCron to be disabled without a next run. Got %+v", jj[1])
+	}
+}
+
+func TestScheduler_Update(t *testing.T) {
+	clock := test.NewClock(start)
+	s := scheduler.New(scheduler.WithClock(clock), scheduler.WithLocation(time.UTC))
+
+	err := s.Register("inventoryCron", config.CronConfig{Schedule: "30 0 * * *"}, func(ctx context.Context, args map[string]string) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+	clock.BlockUntil(t, 1)
+
+	if err := s.Update("inventoryCron", config.CronConfig{Schedule: "30 0 * * *", Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if jj := s.Jobs(); !jj[0].Disabled {
+		t.Errorf("Expected inventoryCron to be disabled. Got %+v", jj[0])
+	}
+	// the stopped loop's timer fires without running the job.
+	clock.Advance(30 * time.Minute)
+
+	if err := s.Update("inventoryCron", config.CronConfig{Schedule: "45 0 * * *"}); err != nil {
+		t.Fatal(err)
+	}
+	clock.BlockUntil(t, 1)
+	clock.Advance(15 * time.Minute)
+
+	rr := waitForRuns(t, s, 1)
+	expected := time.Date(2022, 5, 23, 0, 45, 0, 0, time.UTC)
+	if len(rr) != 1 || !rr[0].ScheduledAt.Equal(expected) {
+		t.Errorf("Expected a single run scheduled at %s. Got %+v", expected, rr)
+	}
+
+	if err := s.Update("invoicesCron", config.CronConfig{Schedule: "* * * * *"}); err == nil {
+		t.Error("Expected updating an unregistered job to fail")
+	}
+	if err := s.Update("inventoryCron", config.CronConfig{Schedule: "61 * * * *"}); err == nil {
+		t.Error("Expected an invalid schedule to fail")
+	}
+}
+
+func TestScheduler_PreventsOverlappingRuns(t *testing.T) {
+	clock := test.NewClock(start)
+	s := scheduler.New(scheduler.WithClock(clock), scheduler.WithLocation(time.UTC))
+
+	release := make(chan struct{})
+	err := s.Register("inventoryCron", config.CronConfig{Schedule: "* * * * *"}, func(ctx context.Context, args map[string]string) error {
+		<-release
+		return errors.New("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+
+	clock.BlockUntil(t, 1)
+	clock.Advance(time.Minute) // first run starts and blocks
+	clock.BlockUntil(t, 1)
+	clock.Advance(time.Minute) // second run is skipped
+
+	rr := waitForRuns(t, s, 1)
+	if !rr[0].Skipped {
+		t.Errorf("Expected the overlapping run to be skipped. Got %+v", rr[0])
+	}
+
+	close(release)
+	rr = waitForRuns(t, s, 2)
+	if rr[1].Skipped || rr[1].Err != "boom" {
+		t.Errorf("Expected the first run to fail with boom. Got %+v", rr[1])
+	}
+}
+
+func TestScheduler_TimeZone(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skipf("time zone data is not available. %v", err)
+	}
+
+	clock := test.NewClock(start)
+	s := scheduler.New(scheduler.WithClock(clock), scheduler.WithLocation(time.UTC))
+
+	conf := config.CronConfig{Schedule: "0 0 * * *", TimeZone: "America/New_York"}
+	if err := s.Register("inventoryCron", conf, func(ctx context.Context, args map[string]string) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+
+	clock.BlockUntil(t, 1)
+	clock.Advance(4 * time.Hour) // midnight in New York (EDT, UTC-4)
+	rr := waitForRuns(t, s, 1)
+
+	expected := time.Date(2022, 5, 23, 4, 0, 0, 0, time.UTC)
+	if !rr[0].ScheduledAt.Equal(expected) {
+		t.Errorf("Expected run to be scheduled at %s. Got %s", expected, rr[0].ScheduledAt)
+	}
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+	clock := test.NewClock(start)
+	s := scheduler.New(
+		scheduler.WithClock(clock),
+		scheduler.WithLocation(time.UTC),
+		scheduler.WithJitter(30*time.Second),
+	)
+	if err := s.Register("inventoryCron", config.CronConfig{Schedule: "* * * * *"}, func(ctx context.Context, args map[string]string) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+
+	clock.BlockUntil(t, 1)
+	clock.Advance(time.Minute + 30*time.Second)
+	rr := waitForRuns(t, s, 1)
+
+	delay := rr[0].StartedAt.Sub(rr[0].ScheduledAt)
+	if delay < 0 || delay > 30*time.Second {
+		t.Errorf("Expected jitter within 30s. Got %s", delay)
+	}
+}
+
+func TestScheduler_Register(t *testing.T) {
+	s := scheduler.New()
+	noop := func(ctx context.Context, args map[string]string) error { return nil }
+
+	if err := s.Register("inventoryCron", config.CronConfig{Schedule: "30 0 * *"}, noop); err == nil {
+		t.Error("Expected invalid schedule to fail")
+	}
+	if err := s.Register("inventoryCron", config.CronConfig{Schedule: "30 0 * * *", TimeZone: "Mars/Olympus"}, noop); err == nil {
+		t.Error("Expected invalid time zone to fail")
+	}
+	if err := s.Register("inventoryCron", config.CronConfig{Schedule: "30 0 * * *"}, noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register("inventoryCron", config.CronConfig{Schedule: "30 0 * * *"}, noop); err == nil {
+		t.Error("Expected duplicate job to fail")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	clock := test.NewClock(start)
+	s := scheduler.New(scheduler.WithClock(clock), scheduler.WithLocation(time.UTC))
+	if err := s.Register("inventoryCron", config.CronConfig{Schedule: "30 0 * * *"}, func(ctx context.Context, args map[string]string) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+	clock.BlockUntil(t, 1)
+	clock.Advance(30 * time.Minute)
+	waitForRuns(t, s, 1)
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	var resp struct {
+		Jobs    []scheduler.JobStatus
+		History []scheduler.Run
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Jobs) != 1 || len(resp.History) != 1 {
+		t.Errorf("Expected 1 job and 1 run. Got %+v", resp)
+	}
+}
+
+func TestScheduler_RunHook(t *testing.T) {
+	clock := test.NewClock(start)
+
+	hooked := make(chan scheduler.Run, 1)
+	s := scheduler.New(
+		scheduler.WithClock(clock),
+		scheduler.WithLocation(time.UTC),
+		scheduler.WithRunHook(func(run scheduler.Run, conf config.CronConfig) {
+			if len(conf.NotifyEmail) != 1 {
+				t.Errorf("Expected the job config to be passed to the hook. Got %+v", conf)
+			}
+			hooked <- run
+		}),
+	)
+	conf := config.CronConfig{Schedule: "* * * * *", NotifyEmail: []string{"jdoe@gmail.com"}}
+	if err := s.Register("inventoryCron", conf, func(ctx context.Context, args map[string]string) error { return errors.New("boom") }); err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+
+	clock.BlockUntil(t, 1)
+	clock.Advance(time.Minute)
+
+	select {
+	case run := <-hooked:
+		if run.Err != "boom" {
+			t.Errorf("Expected the failed run to be passed to the hook. Got %+v", run)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the hook")
+	}
+}
+
+// attemptsLocker reports every TryLock call.
+type attemptsLocker struct {
+	lock.Locker
+	attempts chan struct{}
+}
+
+func (l attemptsLocker) TryLock(ctx context.Context, name string) (lock.Lock, bool, error) {
+	defer func() { l.attempts <- struct{}{} }()
+	return l.Locker.TryLock(ctx, name)
+}
+
+func TestScheduler_Locker(t *testing.T) {
+	clock := test.NewClock(start)
+	locker := attemptsLocker{Locker: lock.NewMemory(), attempts: make(chan struct{}, 10)}
+
+	release := make(chan struct{})
+	job := func(ctx context.Context, args map[string]string) error {
+		<-release
+		return nil
+	}
+
+	// two replicas sharing the locker
+	var replicas []*scheduler.Scheduler
+	for i := 0; i < 2; i++ {
+		s := scheduler.New(scheduler.WithClock(clock), scheduler.WithLocation(time.UTC), scheduler.WithLocker(locker))
+		if err := s.Register("inventoryCron", config.CronConfig{Schedule: "* * * * *"}, job); err != nil {
+			t.Fatal(err)
+		}
+		startScheduler(t, s)
+		replicas = append(replicas, s)
+	}
+
+	clock.BlockUntil(t, 2)
+	clock.Advance(time.Minute)
+	// wait for both replicas to try to lock the job before letting it finish.
+	<-locker.attempts
+	<-locker.attempts
+	close(release)
+
+	var total int
+	deadline := time.Now().Add(5 * time.Second)
+	for total == 0 && time.Now().Before(deadline) {
+		total = len(replicas[0].History()) + len(replicas[1].History())
+		time.Sleep(time.Millisecond)
+	}
+	if total != 1 {
+		t.Errorf("Expected the job to run once across replicas. Got %d runs", total)
+	}
+}
+
+// releasesLocker reports every released lock.
+type releasesLocker struct {
+	lock.Locker
+	releases chan struct{}
+}
+
+func (l releasesLocker) TryLock(ctx context.Context, name string) (lock.Lock, bool, error) {
+	lk, ok, err := l.Locker.TryLock(ctx, name)
+	if !ok {
+		l.releases <- struct{}{}
+		return lk, ok, err
+	}
+	return releasedLock{Lock: lk, releases: l.releases}, ok, err
+}
+
+type releasedLock struct {
+	lock.Lock
+	releases chan struct{}
+}
+
+func (l releasedLock) Unlock(ctx context.Context) error {
+	defer func() { l.releases <- struct{}{} }()
+	return l.Lock.Unlock(ctx)
+}
+
+func TestScheduler_LockerLateTick(t *testing.T) {
+	locker := releasesLocker{Locker: lock.NewMemory(), releases: make(chan struct{}, 10)}
+	job := func(ctx context.Context, args map[string]string) error { return nil }
+
+	// two replicas sharing the locker, with skewed clocks and jitter, so each fires a tick after the other
+	// one finished it.
+	var (
+		replicas []*scheduler.Scheduler
+		clocks   []*test.Clock
+	)
+	for i := 0; i < 2; i++ {
+		clock := test.NewClock(start)
+		s := scheduler.New(
+			scheduler.WithClock(clock),
+			scheduler.WithLocation(time.UTC),
+			scheduler.WithLocker(locker),
+			scheduler.WithJitter(30*time.Second),
+		)
+		if err := s.Register("inventoryCron", config.CronConfig{Schedule: "* * * * *"}, job); err != nil {
+			t.Fatal(err)
+		}
+		startScheduler(t, s)
+		replicas = append(replicas, s)
+		clocks = append(clocks, clock)
+	}
+
+	for tick := 0; tick < 3; tick++ {
+		// the replicas take turns firing first.
+		for i := range clocks {
+			clock := clocks[(tick+i)%2]
+			clock.BlockUntil(t, 1)
+			// past the tick and its jitter, before the next tick.
+			if tick == 0 {
+				clock.Advance(time.Minute + 30*time.Second)
+			} else {
+				clock.Advance(time.Minute)
+			}
+			select {
+			case <-locker.releases:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the run")
+			}
+		}
+	}
+
+	// runs are recorded after their lock is released.
+	runs := map[time.Time]int{}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(runs) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		runs = map[time.Time]int{}
+		for _, s := range replicas {
+			for _, run := range s.History() {
+				runs[run.ScheduledAt]++
+			}
+		}
+	}
+	for tick := 1; tick <= 3; tick++ {
+		scheduledAt := start.Add(time.Duration(tick) * time.Minute)
+		if runs[scheduledAt] != 1 {
+			t.Errorf("Expected the tick at %s to run once across replicas. Got %d runs", scheduledAt, runs[scheduledAt])
+		}
+	}
+}
+
+func TestScheduler_RetriesTimeoutArgs(t *testing.T) {
+	clock := test.NewClock(start)
+	s := scheduler.New(scheduler.WithClock(clock), scheduler.WithLocation(time.UTC), scheduler.WithRetryBackoff(0))
+
+	var calls []map[string]string
+	conf := config.CronConfig{
+		Schedule: "* * * * *",
+		Timeout:  10 * time.Millisecond,
+		Retries:  2,
+		Args:     map[string]string{"warehouse": "main"},
+	}
+	err := s.Register("inventoryCron", conf, func(ctx context.Context, args map[string]string) error {
+		calls = append(calls, args)
+		if len(calls) < 3 {
+			// times out.
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+
+	clock.BlockUntil(t, 1)
+	clock.Advance(time.Minute)
+	rr := waitForRuns(t, s, 1)
+
+	if rr[0].Err != "" || rr[0].Attempts != 3 {
+		t.Errorf("Expected the run to succeed on the third attempt. Got %+v", rr[0])
+	}
+	for _, args := range calls {
+		if args["warehouse"] != "main" {
+			t.Errorf("Expected the job to get its args. Got %v", args)
+		}
+	}
+}
+
+func TestScheduler_RetriesExhausted(t *testing.T) {
+	clock := test.NewClock(start)
+	s := scheduler.New(scheduler.WithClock(clock), scheduler.WithLocation(time.UTC), scheduler.WithRetryBackoff(0))
+
+	conf := config.CronConfig{Schedule: "* * * * *", Timeout: 10 * time.Millisecond, Retries: 1}
+	err := s.Register("inventoryCron", conf, func(ctx context.Context, args map[string]string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	startScheduler(t, s)
+
+	clock.BlockUntil(t, 1)
+	clock.Advance(time.Minute)
+	rr := waitForRuns(t, s, 1)
+
+	expectedErr := "timed out after 10ms. context deadline exceeded"
+	if rr[0].Err != expectedErr || rr[0].Attempts != 2 {
+		t.Errorf("Expected the run to fail after 2 attempts with %q. Got %+v", expectedErr, rr[0])
+	}
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock is a fake clock to be used in tests. Time only moves when Advance is called.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+	changed chan struct{}
+}
+
+type waiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// NewClock returns a fake clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the current fake time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the fake time once the clock is advanced past d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, waiter{until: c.now.Add(d), ch: ch})
+	c.notify()
+	return ch
+}
+
+// Advance moves the clock forward by d and fires every expired After channel.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	ww := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.until.After(c.now) {
+			ww = append(ww, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = ww
+	c.notify()
+}
+
+// BlockUntil blocks until n goroutines are waiting on After. It fails the test after 5 seconds.
+func (c *Clock) BlockUntil(t *testing.T, n int) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		c.mu.Lock()
+		waiting, changed := len(c.waiters), c.changed
+		c.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timeout:
+			t.Fatalf("timed out waiting for %d waiters. got %d", n, waiting)
+		}
+	}
+}
+
+// notify wakes up BlockUntil. Must be called with c.mu held.
+func (c *Clock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
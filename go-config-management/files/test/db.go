@@ -0,0 +1,62 @@
+package test
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"code.com/loader"
+
+	// Postgres driver
+	_ "github.com/jackc/pgx/v4/stdlib"
+)
+
+// SetupDB sets up a database connection to be used in tests.
+// It creates a new schema with the t.Name(), shared by the connections set up in the same test.
+// Once the test is complete, it will drop the created schema and close the db connection.
+// The test fails if the database is not reachable, unless it runs with -short.
+func SetupDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	p := loader.Postgres{Host: "localhost", Port: 5433, User: "postgres", Password: "postgres", Database: "postgres", SSLMode: "disable"}
+	if u := os.Getenv("DATABASE_URL"); u != "" {
+		var err error
+		if p, err = loader.ParsePostgres(u); err != nil {
+			t.Fatalf("invalid DATABASE_URL. err: %v", err)
+		}
+	}
+
+	// every connection of the pool uses the test schema, not just the one a SET would run on.
+	schemaName := strings.ToLower(strings.ReplaceAll(t.Name(), "/", "_"))
+	p.SearchPath = schemaName
+
+	db, err := sql.Open("pgx", p.URL())
+	if err != nil {
+		t.Fatalf("db initialization failed. err: %v", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		if testing.Short() {
+			t.Skipf("database is not reachable, skipping in short mode. err: %v", err)
+		}
+		t.Fatalf("database is not reachable, run `docker-compose up -d`. err: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_, err := db.Exec("DROP SCHEMA IF EXISTS " + schemaName + " CASCADE")
+		if err != nil {
+			t.Fatalf("db cleanup failed. err: %v", err)
+		}
+		db.Close()
+	})
+
+	// create test schema
+	_, err = db.Exec("CREATE SCHEMA IF NOT EXISTS " + schemaName)
+	if err != nil {
+		t.Fatalf("schema creation failed. err: %v", err)
+	}
+
+	return db
+}
@@ -1,31 +1,114 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"code.com/loader"
 )
 
+//go:generate go run code.com/loader/cmd/config doc -type=Config -secret-store -o=../CONFIG.md
+
+// Config is the config of the app, read from flags.
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
+	DBHost           string        `flag:"db-host" default:"localhost" validate:"nonempty" usage:"database host."`
+	DBPort           int           `flag:"db-port" default:"5432" validate:"port" usage:"database port."`
+	DBUser           string        `flag:"db-user" default:"postgres" required:"staging,prod" validate:"nonempty" usage:"database user."`
+	DBPassword       loader.Secret `flag:"db-password" default:"postgres" required:"staging,prod" validate:"nonempty" usage:"database password."`
+	DBName           string        `flag:"db-name" default:"postgres" validate:"nonempty" usage:"database name."`
+	DBSSLMode        string        `flag:"db-sslmode" default:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full" usage:"database ssl mode."`
+	DBSearchPath     string        `flag:"db-search-path" usage:"schemas to look up tables in, e.g. app,public."`
+	DBAppName        string        `flag:"db-application-name" default:"flags" usage:"name of the app in pg_stat_activity."`
+	DBConnectTimeout time.Duration `flag:"db-connect-timeout" default:"5s" validate:"min=0s" usage:"database connect timeout, rounded up to seconds. 0 waits forever."`
 	// ...
 }
 
-func Parse() Config {
-	dbHost := flag.String("db-host", "localhost", "database host.")
-	dbPort := flag.String("db-port", "5432", "database port.")
-	dbUser := flag.String("db-user", "postgres", "database user.")
-	dbPass := flag.String("db-password", "postgres", "database password.")
+// Parse parses the config from the command line flags, see ParseArgs.
+func Parse() (Config, error) {
+	return ParseArgs(os.Args[1:], os.LookupEnv)
+}
+
+// ParseArgs parses the config from the flags in args. The defaults of the database credentials are only
+// good enough for local development, they have to be set explicitly when the APP_ENV var looked up with env
+// is staging or prod. Values like secret://db/password are read from the secret store configured by env,
+// see loader.SecretsFromEnv. An existing DATABASE_URL env var sets the -db flags that aren't given.
+// The error lists every missing or invalid field, or is flag.ErrHelp if -help was given.
+func ParseArgs(args []string, env func(string) (string, bool)) (Config, error) {
+	fs := flag.NewFlagSet("flags", flag.ContinueOnError)
+	profile, _ := env("APP_ENV")
+	secrets, err := loader.SecretsFromEnv(env)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid secret store config. %v", err)
+	}
+	if dsn, _ := env("DATABASE_URL"); dsn != "" {
+		urlArgs, err := databaseURLArgs(dsn)
+		if err != nil {
+			return Config{}, err
+		}
+		// the last value of a flag wins, so the flags in args override the ones from the url.
+		args = append(urlArgs, args...)
+	}
+
+	var c Config
+	if _, err := loader.Load(&c, loader.WithFlags(fs, args), loader.WithProfile(profile), loader.WithSecrets(secrets)); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return Config{}, err
+		}
+		return Config{}, fmt.Errorf("invalid config. %v", err)
+	}
+	return c, nil
+}
+
+// databaseURLArgs returns the -db flags set by the parts of the database url dsn.
+func databaseURLArgs(dsn string) ([]string, error) {
+	p, err := loader.ParsePostgres(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DATABASE_URL. %v", err)
+	}
 
-	flag.Parse()
+	var args []string
+	add := func(name, v string) {
+		if v != "" {
+			args = append(args, "-"+name+"="+v)
+		}
+	}
+	add("db-host", p.Host)
+	if p.Port != 0 {
+		add("db-port", strconv.Itoa(p.Port))
+	}
+	add("db-user", p.User)
+	add("db-password", p.Password.Value())
+	add("db-name", p.Database)
+	add("db-sslmode", p.SSLMode)
+	add("db-search-path", p.SearchPath)
+	add("db-application-name", p.AppName)
+	if p.ConnectTimeout != 0 {
+		add("db-connect-timeout", p.ConnectTimeout.String())
+	}
+	return args, nil
+}
 
-	c := Config{
-		DBHost:     *dbHost,
-		DBPort:     *dbPort,
-		DBUser:     *dbUser,
-		DBPassword: *dbPass,
+// Postgres returns the database connection config, whose URL and DSN methods build the connection strings.
+func (c Config) Postgres() loader.Postgres {
+	return loader.Postgres{
+		Host:           c.DBHost,
+		Port:           c.DBPort,
+		User:           c.DBUser,
+		Password:       c.DBPassword,
+		Database:       c.DBName,
+		SSLMode:        c.DBSSLMode,
+		SearchPath:     c.DBSearchPath,
+		AppName:        c.DBAppName,
+		ConnectTimeout: c.DBConnectTimeout,
 	}
+}
 
-	return c
+// Dump writes the config to w with the password masked.
+func (c Config) Dump(w io.Writer) error {
+	return loader.Dump(w, c, nil)
 }
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"log"
 
 	"code.com/config"
 )
 
 func main() {
-	c := config.Parse()
+	c, err := config.Parse()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	fmt.Println(c.DBHost)
 }
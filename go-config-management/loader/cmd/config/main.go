@@ -0,0 +1,95 @@
+// Command config generates the documentation of config structs from their tags and comments.
+//
+// Usage:
+//
+//	config doc [-type=Config] [-format=md] [-o=path] [-example=name] [-secret-store] [dir]
+//
+// -type is a comma separated list of the struct types, or maps of structs, to document. dir is the directory
+// of their package, the current one by default. -format is md for a Markdown reference with samples, or env,
+// flags or json for just a sample .env file, command line or config file with the defaults.
+// -example names the entry of the sample files of maps, e.g. a registered job, so the samples can be loaded.
+// -secret-store adds the env vars of loader.SecretsFromEnv, for configs whose secrets are read through it.
+// It is meant for go:generate:
+//
+//	//go:generate go run code.com/loader/cmd/config doc -type=Config -o=../CONFIG.md
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"code.com/loader/doc"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 || os.Args[1] != "doc" {
+		log.Fatal("usage: config doc [-type=Config] [-format=md|env|flags|json] [-o=path] [-example=name] [-secret-store] [dir]")
+	}
+	if err := run(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("config doc", flag.ContinueOnError)
+	typeNames := fs.String("type", "Config", "comma separated config types to document.")
+	format := fs.String("format", "md", "output format: md, env, flags or json.")
+	out := fs.String("o", "", "file to write to. Defaults to stdout.")
+	program := fs.String("program", "go run .", "program of the sample command line.")
+	example := fs.String("example", "", "name of the entry of the sample files of maps. Defaults to example.")
+	secretStore := fs.Bool("secret-store", false, "document the env vars of loader.SecretsFromEnv.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	tt, err := doc.Parse(dir, strings.Split(*typeNames, ",")...)
+	if err != nil {
+		return err
+	}
+	for i := range tt {
+		tt[i].Example = *example
+	}
+	if *secretStore {
+		tt = append(tt, doc.SecretStore)
+	}
+
+	var buf bytes.Buffer
+	switch *format {
+	case "md":
+		err = doc.Markdown(&buf, tt, *program)
+	case "env":
+		err = doc.Dotenv(&buf, tt)
+	case "flags":
+		err = doc.Flags(&buf, tt, *program)
+	case "json":
+		for _, t := range tt {
+			if err = doc.JSON(&buf, t); err != nil {
+				break
+			}
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := ioutil.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s. %v", *out, err)
+	}
+	return nil
+}
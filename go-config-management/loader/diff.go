@@ -0,0 +1,102 @@
+package loader
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Change is a config field whose value changed. Old and New are printed like Dump prints them, so secrets
+// and URL passwords are masked. A change of a secret shows up as "[REDACTED]" on both sides.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Diff compares two configs field by field and returns the fields whose value changed, in field order.
+// old and new are structs, or maps of structs keyed by name like cron configs, of the same type or pointers to them.
+// The fields of an entry that was added or removed are compared to their zero value.
+func Diff(old, new interface{}) ([]Change, error) {
+	ov, nv := reflect.Indirect(reflect.ValueOf(old)), reflect.Indirect(reflect.ValueOf(new))
+	if !ov.IsValid() || !nv.IsValid() || ov.Type() != nv.Type() {
+		return nil, errors.New("loader: old and new must be configs of the same type")
+	}
+	if !isRoot(ov.Type()) {
+		return nil, errors.New("loader: configs must be structs or maps with string keys")
+	}
+
+	var changes []Change
+	diff(ov, nv, "", &changes)
+	return changes, nil
+}
+
+func diff(old, new reflect.Value, path string, changes *[]Change) {
+	t := old.Type()
+	switch {
+	case t.Kind() == reflect.Struct && !isText(t):
+		prefix := ""
+		if path != "" {
+			prefix = path + "."
+		}
+		nf := collect(new, prefix)
+		for i, f := range collect(old, prefix) {
+			diff(f.value, nf[i].value, f.path, changes)
+		}
+		return
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.Struct && !isText(t.Elem()):
+		keys := map[string]bool{}
+		for _, k := range append(old.MapKeys(), new.MapKeys()...) {
+			keys[k.String()] = true
+		}
+		names := make([]string, 0, len(keys))
+		for k := range keys {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			k := reflect.ValueOf(name).Convert(t.Key())
+			diff(entry(old, k), entry(new, k), index(path, name), changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+		*changes = append(*changes, Change{Field: path, Old: dumpValue(old), New: dumpValue(new)})
+	}
+}
+
+// entry returns the entry key of the map m, or the zero value of its entries if m doesn't have one.
+func entry(m, key reflect.Value) reflect.Value {
+	if v := m.MapIndex(key); v.IsValid() {
+		return v
+	}
+	return reflect.Zero(m.Type().Elem())
+}
+
+// LogChanges logs every change on its own line as key=value pairs, e.g.
+//
+//	config changed source=cron_config.json field=inventoryCron.Schedule old="30 0 * * *" new="0 1 * * *"
+//
+// source names what the configs were loaded from.
+func LogChanges(logger *log.Logger, source string, changes []Change) {
+	for _, c := range changes {
+		logger.Printf("config changed source=%s field=%s old=%s new=%s", logfmt(source), logfmt(c.Field), logfmt(c.Old), logfmt(c.New))
+	}
+}
+
+// logfmt quotes s if it has characters that would split a key=value pair. Strings are already quoted by dumpValue.
+func logfmt(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		if _, err := strconv.Unquote(s); err == nil {
+			return s
+		}
+	}
+	if s == "" || strings.ContainsAny(s, " \t\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,212 @@
+// Package doc documents config structs from their source: the loader tags of their fields and their comments.
+// Comments are only in the source, so the package is parsed with go/parser instead of being reflected on.
+// The example tag of a field, e.g. example:"30 0 * * *", is its value in the samples instead of the default.
+package doc
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"code.com/loader"
+)
+
+// Type is a documented config struct.
+type Type struct {
+	Name    string
+	Doc     string
+	IsMap   bool   // The type is a map of the struct keyed by name, e.g. jobs by name
+	Example string // Name of the entry in the samples of maps, e.g. a registered job. Defaults to example
+	Fields  []Field
+}
+
+// Field is a leaf field of a config struct.
+type Field struct {
+	Path     string // e.g. "DB.Host" for nested structs
+	Key      string // Key path in config files, e.g. "db.host"
+	Env      string
+	Flag     string
+	Default  string
+	Example  string // Value in the samples instead of the default
+	Required string // "true" or the profiles the field is required in
+	Validate string
+	Type     string // Go type, e.g. "time.Duration"
+	Doc      string // Usage tag followed by the comments of the field
+	HasKey   bool   // The field has a json tag, so it is meant to be set by config files
+}
+
+// IsSecret reports whether the field is a loader.Secret, whose default is not shown.
+func (f Field) IsSecret() bool {
+	return f.Type == "loader.Secret" || f.Type == "Secret"
+}
+
+// SecretStore documents the env vars of the secret store secret:// references are read from, for configs
+// loaded with loader.SecretsFromEnv.
+var SecretStore = Type{
+	Name: "Secret store",
+	Doc:  "Values like secret://db/password are read from the secret store configured by these env vars, see loader.SecretsFromEnv.",
+	Fields: []Field{
+		{Env: loader.EnvVaultAddr, Type: "string", Doc: "address of a Vault server with a KV v2 engine, e.g. https://vault:8200."},
+		{Env: loader.EnvVaultToken, Type: "loader.Secret", Doc: "token of the Vault server. Required with " + loader.EnvVaultAddr + "."},
+		{Env: loader.EnvVaultMount, Type: "string", Default: "secret", Doc: "mount path of the KV v2 engine."},
+		{Env: loader.EnvSecretsFile, Type: "string", Doc: "path of a secrets file encrypted with loader.EncryptSecrets, used when " + loader.EnvVaultAddr + " is empty."},
+		{Env: loader.EnvSecretsKey, Type: "loader.Secret", Doc: "32 base64 encoded bytes the secrets file is encrypted with. Required with " + loader.EnvSecretsFile + "."},
+	},
+}
+
+// Parse parses the package in dir and documents its types names, which must be structs or maps of structs.
+func Parse(dir string, names ...string) ([]Type, error) {
+	fset := token.NewFileSet()
+	notTest := func(fi os.FileInfo) bool { return !strings.HasSuffix(fi.Name(), "_test.go") }
+	pkgs, err := parser.ParseDir(fset, dir, notTest, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse package %s. %v", dir, err)
+	}
+
+	specs := map[string]*ast.TypeSpec{}
+	docs := map[string]string{}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					specs[ts.Name.Name] = ts
+					doc := ts.Doc
+					if doc == nil {
+						doc = gd.Doc
+					}
+					docs[ts.Name.Name] = text(doc)
+				}
+			}
+		}
+	}
+
+	p := &parsed{specs: specs}
+	var tt []Type
+	for _, name := range names {
+		ts, ok := specs[name]
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in %s", name, dir)
+		}
+		t := Type{Name: name, Doc: docs[name]}
+
+		st, ok := ts.Type.(*ast.StructType)
+		if mt, isMap := ts.Type.(*ast.MapType); isMap {
+			t.IsMap = true
+			st, ok = p.structOf(mt.Value)
+		}
+		if !ok {
+			return nil, fmt.Errorf("type %s is not a struct or a map of structs", name)
+		}
+		t.Fields = p.fields(st, "", "")
+		tt = append(tt, t)
+	}
+	return tt, nil
+}
+
+type parsed struct {
+	specs map[string]*ast.TypeSpec
+}
+
+// structOf returns the struct the type expression e names if it is a struct of the package.
+func (p *parsed) structOf(e ast.Expr) (*ast.StructType, bool) {
+	id, ok := e.(*ast.Ident)
+	if !ok {
+		return nil, false
+	}
+	ts, ok := p.specs[id.Name]
+	if !ok {
+		return nil, false
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	return st, ok
+}
+
+// fields returns the leaf fields of st. Like loader.Load, nested structs are walked unless they have
+// a source tag themselves. Fields without any loader tag, e.g. ones set by the code, are skipped.
+func (p *parsed) fields(st *ast.StructType, path, keyPath string) []Field {
+	var ff []Field
+	for _, af := range st.Fields.List {
+		var tag reflect.StructTag
+		if af.Tag != nil {
+			s, _ := strconv.Unquote(af.Tag.Value)
+			tag = reflect.StructTag(s)
+		}
+		for _, id := range af.Names {
+			if !id.IsExported() {
+				continue
+			}
+			jsonKey, hasKey := id.Name, false
+			if j := strings.Split(tag.Get("json"), ",")[0]; j == "-" {
+				continue
+			} else if j != "" {
+				jsonKey, hasKey = j, true
+			}
+
+			f := Field{
+				Path:     join(path, id.Name),
+				Key:      join(keyPath, jsonKey),
+				Env:      tag.Get("env"),
+				Flag:     tag.Get("flag"),
+				Default:  tag.Get("default"),
+				Example:  tag.Get("example"),
+				Required: tag.Get("required"),
+				Validate: tag.Get("validate"),
+				Type:     types.ExprString(af.Type),
+				Doc:      description(tag.Get("usage"), text(af.Doc), text(af.Comment)),
+				HasKey:   hasKey,
+			}
+			hasSource := f.Env != "" || f.Flag != "" || f.Default != ""
+			if nested, ok := p.structOf(af.Type); ok && !hasSource {
+				ff = append(ff, p.fields(nested, f.Path, f.Key)...)
+				continue
+			}
+			if !hasSource && !hasKey && f.Required == "" && f.Validate == "" {
+				continue
+			}
+			ff = append(ff, f)
+		}
+	}
+	return ff
+}
+
+// text returns the text of a comment group without the "..." placeholders.
+func text(cg *ast.CommentGroup) string {
+	s := strings.TrimSpace(cg.Text())
+	if s == "..." {
+		return ""
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// description joins the usage tag and the comments of a field into sentences.
+func description(parts ...string) string {
+	var ss []string
+	for _, s := range parts {
+		if s == "" {
+			continue
+		}
+		if !strings.HasSuffix(s, ".") {
+			s += "."
+		}
+		ss = append(ss, s)
+	}
+	return strings.Join(ss, " ")
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,169 @@
+package doc_test
+
+import (
+	"bytes"
+	"testing"
+
+	"code.com/loader/doc"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	tt, err := doc.Parse("testdata/app", "Config", "Jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []doc.Type{
+		{
+			Name: "Config",
+			Doc:  "Config is the config of the app.",
+			Fields: []doc.Field{
+				{Path: "DBHost", Key: "DBHost", Env: "DB_HOST", Flag: "db-host", Default: "localhost", Validate: "nonempty", Type: "string", Doc: "Host of database server."},
+				{Path: "DBPassword", Key: "DBPassword", Env: "DB_PASSWORD", Default: "postgres", Required: "staging,prod", Type: "loader.Secret"},
+				{Path: "Timeout", Key: "Timeout", Flag: "timeout", Default: "5s", Type: "time.Duration", Doc: "request timeout."},
+				{Path: "Debug", Key: "debug", Type: "bool", HasKey: true},
+				{Path: "DB.Pool", Key: "db.pool", Default: "10", Type: "int", Doc: "connections in the pool.", HasKey: true},
+			},
+		},
+		{
+			Name:  "Jobs",
+			Doc:   "Jobs are the jobs by name.",
+			IsMap: true,
+			Fields: []doc.Field{
+				{Path: "Schedule", Key: "schedule", Example: "@daily", Required: "true", Type: "string", HasKey: true},
+				{Path: "Emails", Key: "emails", Type: "[]string", Doc: "Notified when a run fails.", HasKey: true},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, tt); diff != "" {
+		t.Errorf("types are different (-want +got):\n%s", diff)
+	}
+
+	if _, err := doc.Parse("testdata/app", "Missing"); err == nil {
+		t.Error("Expected a missing type to fail")
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	tt, err := doc.Parse("testdata/app", "Config", "Jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Markdown(&buf, tt, "app"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# Config reference\n" +
+		"\n" +
+		"<!-- Generated by `config doc`, do not edit. -->\n" +
+		"\n" +
+		"## Config\n" +
+		"\n" +
+		"Config is the config of the app.\n" +
+		"\n" +
+		"| Env var | Flag | Key | Type | Default | Required | Validation | Description |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- | --- |\n" +
+		"| `DB_HOST` | `-db-host` |  | `string` | `localhost` |  | `nonempty` | Host of database server. |\n" +
+		"| `DB_PASSWORD` |  |  | `loader.Secret` |  | in staging, prod |  |  |\n" +
+		"|  | `-timeout` |  | `time.Duration` | `5s` |  |  | request timeout. |\n" +
+		"|  |  | `debug` | `bool` |  |  |  |  |\n" +
+		"|  |  | `db.pool` | `int` | `10` |  |  | connections in the pool. |\n" +
+		"\n" +
+		"## Jobs\n" +
+		"\n" +
+		"Jobs are the jobs by name.\n" +
+		"\n" +
+		"| Key | Type | Required | Description |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| `<name>.schedule` | `string` | yes |  |\n" +
+		"| `<name>.emails` | `[]string` |  | Notified when a run fails. |\n" +
+		"\n" +
+		"## Sample .env\n" +
+		"\n" +
+		"```sh\n" +
+		"# Host of database server.\n" +
+		"DB_HOST=localhost\n" +
+		"# Required in staging, prod.\n" +
+		"DB_PASSWORD=\n" +
+		"```\n" +
+		"\n" +
+		"## Sample flags\n" +
+		"\n" +
+		"```sh\n" +
+		"app \\\n" +
+		"  -db-host=localhost \\\n" +
+		"  -timeout=5s\n" +
+		"```\n" +
+		"\n" +
+		"## Sample Config file\n" +
+		"\n" +
+		"```json\n" +
+		"{\n" +
+		"  \"db\": {\n" +
+		"    \"pool\": 10\n" +
+		"  },\n" +
+		"  \"debug\": false\n" +
+		"}\n" +
+		"```\n" +
+		"\n" +
+		"## Sample Jobs file\n" +
+		"\n" +
+		"```json\n" +
+		"{\n" +
+		"  \"example\": {\n" +
+		"    \"emails\": [],\n" +
+		"    \"schedule\": \"@daily\"\n" +
+		"  }\n" +
+		"}\n" +
+		"```\n"
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Errorf("markdown is different (-want +got):\n%s", diff)
+	}
+}
+
+func TestJSON_Example(t *testing.T) {
+	tt, err := doc.Parse("testdata/app", "Jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt[0].Example = "backup"
+
+	var buf bytes.Buffer
+	if err := doc.JSON(&buf, tt[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "{\n" +
+		"  \"backup\": {\n" +
+		"    \"emails\": [],\n" +
+		"    \"schedule\": \"@daily\"\n" +
+		"  }\n" +
+		"}\n"
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Errorf("sample is different (-want +got):\n%s", diff)
+	}
+}
+
+func TestDotenv_SecretStore(t *testing.T) {
+	var buf bytes.Buffer
+	if err := doc.Dotenv(&buf, []doc.Type{doc.SecretStore}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# address of a Vault server with a KV v2 engine, e.g. https://vault:8200.\n" +
+		"VAULT_ADDR=\n" +
+		"# token of the Vault server. Required with VAULT_ADDR.\n" +
+		"VAULT_TOKEN=\n" +
+		"# mount path of the KV v2 engine.\n" +
+		"VAULT_MOUNT=secret\n" +
+		"# path of a secrets file encrypted with loader.EncryptSecrets, used when VAULT_ADDR is empty.\n" +
+		"SECRETS_FILE=\n" +
+		"# 32 base64 encoded bytes the secrets file is encrypted with. Required with SECRETS_FILE.\n" +
+		"SECRETS_KEY=\n"
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Errorf("sample is different (-want +got):\n%s", diff)
+	}
+}
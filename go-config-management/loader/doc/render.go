@@ -0,0 +1,275 @@
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Markdown writes a reference of the types: a table of their fields with the env var, flag and
+// config file key that set them, their default, when they are required and their description,
+// followed by the samples of Dotenv, Flags and JSON the fields can be set with.
+// Columns no field of a type uses are left out.
+func Markdown(w io.Writer, tt []Type, program string) error {
+	var b strings.Builder
+	b.WriteString("# Config reference\n\n<!-- Generated by `config doc`, do not edit. -->\n")
+	for _, t := range tt {
+		fmt.Fprintf(&b, "\n## %s\n\n", t.Name)
+		if t.Doc != "" {
+			fmt.Fprintf(&b, "%s\n\n", t.Doc)
+		}
+
+		cols := []column{
+			{"Env var", func(f Field) string { return code(f.Env) }},
+			{"Flag", func(f Field) string { return code(prefix("-", f.Flag)) }},
+			{"Key", func(f Field) string { return code(keyOf(t, f)) }},
+			{"Type", func(f Field) string { return code(f.Type) }},
+			{"Default", func(f Field) string { return code(defaultOf(f)) }},
+			{"Required", func(f Field) string { return required(f.Required) }},
+			{"Validation", func(f Field) string { return code(f.Validate) }},
+			{"Description", func(f Field) string { return f.Doc }},
+		}
+		var used []column
+		for _, c := range cols {
+			for _, f := range t.Fields {
+				if c.value(f) != "" || c.name == "Description" {
+					used = append(used, c)
+					break
+				}
+			}
+		}
+
+		row := func(cells []string) {
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(cells, " | "))
+		}
+		var header, sep []string
+		for _, c := range used {
+			header = append(header, c.name)
+			sep = append(sep, "---")
+		}
+		row(header)
+		row(sep)
+		for _, f := range t.Fields {
+			var cells []string
+			for _, c := range used {
+				cells = append(cells, strings.ReplaceAll(c.value(f), "|", `\|`))
+			}
+			row(cells)
+		}
+	}
+
+	samples := []sample{
+		{".env", "sh", tt, func(f Field) bool { return f.Env != "" }, func(w io.Writer) error { return Dotenv(w, tt) }},
+		{"flags", "sh", tt, func(f Field) bool { return f.Flag != "" }, func(w io.Writer) error { return Flags(w, tt, program) }},
+	}
+	for _, t := range tt {
+		t := t
+		samples = append(samples, sample{t.Name + " file", "json", []Type{t}, func(f Field) bool { return f.HasKey }, func(w io.Writer) error { return JSON(w, t) }})
+	}
+	for _, s := range samples {
+		if !hasField(s.types, s.has) {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## Sample %s\n\n```%s\n", s.title, s.lang)
+		if err := s.write(&b); err != nil {
+			return err
+		}
+		b.WriteString("```\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func hasField(tt []Type, fn func(Field) bool) bool {
+	for _, t := range tt {
+		for _, f := range t.Fields {
+			if fn(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sample is a sample of the fields of types has is true for.
+type sample struct {
+	title, lang string
+	types       []Type
+	has         func(Field) bool
+	write       func(io.Writer) error
+}
+
+type column struct {
+	name  string
+	value func(Field) string
+}
+
+// Dotenv writes a sample .env file setting every env var to its default, with its description as a comment.
+func Dotenv(w io.Writer, tt []Type) error {
+	var b strings.Builder
+	for _, t := range tt {
+		for _, f := range t.Fields {
+			if f.Env == "" {
+				continue
+			}
+			if comment := strings.TrimSpace(f.Doc + requiredNote(f.Required)); comment != "" {
+				fmt.Fprintf(&b, "# %s\n", comment)
+			}
+			fmt.Fprintf(&b, "%s=%s\n", f.Env, defaultOf(f))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Flags writes a sample command line passing every flag with its default.
+func Flags(w io.Writer, tt []Type, program string) error {
+	lines := []string{program}
+	for _, t := range tt {
+		for _, f := range t.Fields {
+			if f.Flag == "" {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("  -%s=%s", f.Flag, shellQuote(defaultOf(f))))
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s\n", strings.Join(lines, " \\\n"))
+	return err
+}
+
+// JSON writes a sample config file of t with the fields with a json tag set to their examples, defaults, or the
+// zero value of their type. Maps of structs are written with a single entry named t.Example, or example.
+func JSON(w io.Writer, t Type) error {
+	root := map[string]interface{}{}
+	obj := root
+	if t.IsMap {
+		name := t.Example
+		if name == "" {
+			name = "example"
+		}
+		obj = map[string]interface{}{}
+		root[name] = obj
+	}
+	for _, f := range t.Fields {
+		if !f.HasKey {
+			continue
+		}
+		set(obj, strings.Split(f.Key, "."), sampleValue(f))
+	}
+
+	bb, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sample. %v", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", bb)
+	return err
+}
+
+func set(obj map[string]interface{}, keys []string, v interface{}) {
+	for _, k := range keys[:len(keys)-1] {
+		child, ok := obj[k].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			obj[k] = child
+		}
+		obj = child
+	}
+	obj[keys[len(keys)-1]] = v
+}
+
+// sampleValue returns the example or the default of f as a JSON value, or the zero value of its type.
+func sampleValue(f Field) interface{} {
+	def := defaultOf(f)
+	if f.Example != "" {
+		def = f.Example
+	}
+	switch {
+	case f.Type == "bool":
+		b, _ := strconv.ParseBool(def)
+		return b
+	case strings.HasPrefix(f.Type, "int") || strings.HasPrefix(f.Type, "uint") || strings.HasPrefix(f.Type, "float"):
+		if def == "" {
+			def = "0"
+		}
+		return json.Number(def)
+	case strings.HasPrefix(f.Type, "[]"):
+		if def == "" {
+			return []interface{}{}
+		}
+		var items []interface{}
+		for _, s := range strings.Split(def, ",") {
+			items = append(items, strings.TrimSpace(s))
+		}
+		return items
+	case strings.HasPrefix(f.Type, "map["):
+		return map[string]interface{}{}
+	case f.Type == "time.Duration" && def == "":
+		return "0s"
+	}
+	return def
+}
+
+func keyOf(t Type, f Field) string {
+	if !f.HasKey {
+		return ""
+	}
+	if t.IsMap {
+		return "<name>." + f.Key
+	}
+	return f.Key
+}
+
+// defaultOf returns the default of f, which is left out for secrets.
+func defaultOf(f Field) string {
+	if f.IsSecret() {
+		return ""
+	}
+	return f.Default
+}
+
+func required(req string) string {
+	switch req {
+	case "":
+		return ""
+	case "true":
+		return "yes"
+	}
+	return "in " + strings.ReplaceAll(req, ",", ", ")
+}
+
+func requiredNote(req string) string {
+	switch req {
+	case "":
+		return ""
+	case "true":
+		return " Required."
+	}
+	return " Required " + required(req) + "."
+}
+
+func prefix(p, s string) string {
+	if s == "" {
+		return ""
+	}
+	return p + s
+}
+
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+// shellQuote quotes s for a POSIX shell if it has characters the shell would interpret.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.,:/@=", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"time"
+
+	"code.com/loader"
+)
+
+// Config is the config of the app.
+type Config struct {
+	// ...
+	DBHost     string        `env:"DB_HOST" flag:"db-host" default:"localhost" validate:"nonempty"` // Host of database server
+	DBPassword loader.Secret `env:"DB_PASSWORD" default:"postgres" required:"staging,prod"`
+	Timeout    time.Duration `flag:"timeout" default:"5s" usage:"request timeout"`
+
+	// Jobs are read from the jobs file.
+	Jobs  Jobs
+	Debug bool `json:"debug"`
+	DB    DB   `json:"db"`
+
+	internal string
+}
+
+// DB is a nested struct.
+type DB struct {
+	Pool int `json:"pool" default:"10" usage:"connections in the pool."`
+}
+
+// Jobs are the jobs by name.
+type Jobs map[string]Job
+
+type Job struct {
+	Schedule string   `json:"schedule" required:"true" example:"@daily"`
+	Emails   []string `json:"emails"` // Notified when a run fails
+}
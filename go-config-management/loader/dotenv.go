@@ -0,0 +1,204 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// dotenvValue is a value of a .env file with the line it is on.
+type dotenvValue struct {
+	value string
+	line  int
+}
+
+// dotenvField is a leaf field of the config struct with its .env key.
+type dotenvField struct {
+	field
+	key string
+}
+
+// loadDotenv applies a .env file. Keys are matched to the env tag of fields, or if they don't have one,
+// to the json names of the path of the field joined by "_" and upper cased, e.g. INVENTORYCRON_SCHEDULE.
+func (l *loader) loadDotenv(dst reflect.Value, path string, bb []byte) error {
+	if dst.Kind() != reflect.Struct {
+		return fmt.Errorf("failed to load config file %s. .env files can only be loaded into structs", path)
+	}
+
+	vars, err := parseDotenv(bb)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal config file %s. %v", path, err)
+	}
+
+	fields := dotenvFields(dst, "", "")
+	if l.strict {
+		if err := unknownDotenvKeys(vars, fields); err != nil {
+			return fmt.Errorf("invalid config file %s. %v", path, err)
+		}
+	}
+
+	for _, f := range fields {
+		v, ok := vars[f.key]
+		if !ok {
+			continue
+		}
+		value, err := l.expandString(v.value)
+		if err != nil {
+			return fmt.Errorf("invalid config file %s. line %d: %s: %v", path, v.line, f.key, err)
+		}
+		if err := setString(f.value, value); err != nil {
+			return fmt.Errorf("invalid config file %s. line %d: %s: %v", path, v.line, f.key, err)
+		}
+		l.report[f.path] = SourceFile
+	}
+	return nil
+}
+
+func unknownDotenvKeys(vars map[string]dotenvValue, fields []dotenvField) error {
+	known := make([]string, len(fields))
+	isKnown := map[string]bool{}
+	for i, f := range fields {
+		known[i] = f.key
+		isKnown[f.key] = true
+	}
+
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		if !isKnown[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return vars[keys[i]].line < vars[keys[j]].line })
+
+	errs := make([]string, len(keys))
+	for i, key := range keys {
+		errs[i] = fmt.Sprintf("line %d: %s", vars[key].line, unknownKey("", key, known))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return unknownKeysError(errs)
+}
+
+func dotenvFields(v reflect.Value, path, prefix string) []dotenvField {
+	var ff []dotenvField
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name, skip := jsonName(sf)
+		if skip {
+			continue
+		}
+
+		f := field{path: join(path, sf.Name), value: v.Field(i), tag: sf.Tag}
+		key := prefix + strings.ToUpper(name)
+		if sf.Type.Kind() == reflect.Struct && !hasSourceTag(sf.Tag) && !isText(sf.Type) {
+			ff = append(ff, dotenvFields(f.value, f.path, key+"_")...)
+			continue
+		}
+		if env := sf.Tag.Get("env"); env != "" {
+			key = env
+		}
+		ff = append(ff, dotenvField{field: f, key: key})
+	}
+	return ff
+}
+
+// parseDotenv parses the KEY=value lines of a .env file. Lines may start with "export ", blank lines and
+// lines starting with # are skipped. Values may be double quoted, with \n, \t, \" and \\ escapes,
+// single quoted, taken literally, or unquoted, where a " #" starts a comment.
+func parseDotenv(bb []byte) (map[string]dotenvValue, error) {
+	vars := map[string]dotenvValue{}
+	sc := bufio.NewScanner(bytes.NewReader(bb))
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " \t"))
+		trimmed = strings.TrimPrefix(trimmed, "export ")
+
+		i := strings.Index(trimmed, "=")
+		if i < 0 {
+			return nil, syntaxError{Line: line, Column: indent + 1, Msg: "expected KEY=value"}
+		}
+		key := strings.TrimSpace(trimmed[:i])
+		if col := invalidKeyChar(key); col >= 0 {
+			return nil, syntaxError{Line: line, Column: indent + col + 1, Msg: fmt.Sprintf("invalid key %q", key)}
+		}
+
+		raw := strings.TrimSpace(trimmed[i+1:])
+		value, err := dotenvUnquote(raw)
+		if err != nil {
+			return nil, syntaxError{Line: line, Column: strings.LastIndex(text, raw) + 1, Msg: err.Error()}
+		}
+		vars[key] = dotenvValue{value: value, line: line}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// invalidKeyChar returns the index of the first character of key that is not allowed in env var names, or -1.
+func invalidKeyChar(key string) int {
+	if key == "" {
+		return 0
+	}
+	for i, r := range key {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return i
+		}
+	}
+	return -1
+}
+
+func dotenvUnquote(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		var sb strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			switch {
+			case c == '"':
+				if rest := strings.TrimSpace(raw[i+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+					return "", fmt.Errorf("unexpected %q after closing quote", rest)
+				}
+				return sb.String(), nil
+			case c == '\\' && i+1 < len(raw):
+				i++
+				switch raw[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				default:
+					sb.WriteByte(raw[i])
+				}
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated quoted value")
+	case strings.HasPrefix(raw, `'`):
+		end := strings.Index(raw[1:], `'`)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		return raw[1 : end+1], nil
+	}
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	return raw, nil
+}
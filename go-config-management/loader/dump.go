@@ -0,0 +1,52 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"text/tabwriter"
+)
+
+// Dump writes the effective config, one field per line, with every Secret and URL password masked.
+// cfg is a config struct or a pointer to one. If report is not nil, the source of every field is printed too.
+func Dump(w io.Writer, cfg interface{}, report Report) error {
+	rv := reflect.Indirect(reflect.ValueOf(cfg))
+	if rv.Kind() != reflect.Struct {
+		return errors.New("loader: cfg must be a struct or a pointer to one")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 1, ' ', 0)
+	for _, f := range collect(rv, "") {
+		line := fmt.Sprintf("%s\t= %s", f.path, dumpValue(f.value))
+		if report != nil {
+			src, ok := report[f.path]
+			if !ok {
+				src = "unset"
+			}
+			line += fmt.Sprintf("\t(%s)", src)
+		}
+		if _, err := fmt.Fprintln(tw, line); err != nil {
+			return fmt.Errorf("failed to write config. %v", err)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write config. %v", err)
+	}
+	return nil
+}
+
+func dumpValue(v reflect.Value) string {
+	switch {
+	case v.Type() == urlType:
+		u := v.Interface().(url.URL)
+		return u.Redacted()
+	case v.Kind() == reflect.Ptr && v.Type().Elem() == urlType && !v.IsNil():
+		return v.Interface().(*url.URL).Redacted()
+	case v.Kind() == reflect.String && v.Type() != secretType:
+		return fmt.Sprintf("%q", v.String())
+	}
+	// Secret and other fmt.Stringers redact themselves, including the ones in slices and maps.
+	return fmt.Sprint(v.Interface())
+}
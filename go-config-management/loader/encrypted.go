@@ -0,0 +1,96 @@
+package loader
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// EncryptedFile is a SecretProvider reading secrets from a local file encrypted with AES-256-GCM.
+// The file is the nonce followed by the sealed JSON object of the secrets keyed by their path,
+// e.g. {"db/password": "..."}. See EncryptSecrets to create one.
+type EncryptedFile struct {
+	secrets map[string]string
+}
+
+// OpenEncryptedFile decrypts the secrets file at path with the 32 byte key.
+func OpenEncryptedFile(path string, key []byte) (*EncryptedFile, error) {
+	bb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file. %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(bb) < gcm.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt secrets file %s. file is too short", path)
+	}
+	plain, err := gcm.Open(nil, bb[:gcm.NonceSize()], bb[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets file %s. wrong key or corrupted file", path)
+	}
+
+	var secrets map[string]string
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal secrets file %s. %v", path, err)
+	}
+	return &EncryptedFile{secrets: secrets}, nil
+}
+
+// Secret returns the secret at path.
+func (f *EncryptedFile) Secret(ctx context.Context, path string) (string, error) {
+	v, ok := f.secrets[path]
+	if !ok {
+		return "", fmt.Errorf("secret not found")
+	}
+	return v, nil
+}
+
+// EncryptSecrets encrypts the secrets keyed by their path with the 32 byte key into the content of
+// a file OpenEncryptedFile can read.
+func EncryptSecrets(secrets map[string]string, key []byte) ([]byte, error) {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal secrets. %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce. %v", err)
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// ParseSecretsKey decodes a base64 encoded 32 byte key, e.g. the output of `openssl rand -base64 32`.
+func ParseSecretsKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64. %v", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets key. %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher. %v", err)
+	}
+	return gcm, nil
+}
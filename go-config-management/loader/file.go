@@ -0,0 +1,324 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// loadFiles applies the config files. JSON, YAML and TOML files are deep merged in order, see merge,
+// and applied together. .env files are applied after them.
+func (l *loader) loadFiles(dst reflect.Value) error {
+	var (
+		tree    interface{}
+		names   []string
+		dotenvs []string
+	)
+	for _, f := range l.files {
+		decode, err := decoder(f.path)
+		if err != nil {
+			return err
+		}
+
+		bb, err := ioutil.ReadFile(f.path)
+		if f.optional && os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read config file. %v", err)
+		}
+		if decode == nil {
+			dotenvs = append(dotenvs, f.path)
+			continue
+		}
+
+		t, err := decode(bb)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal config file %s. %v", f.path, err)
+		}
+		if names == nil {
+			tree = t
+		} else {
+			tree = merge(tree, t)
+		}
+		names = append(names, f.path)
+	}
+
+	if names != nil {
+		// "$schema" points editors to the JSON Schema of the file, see Schema.
+		if obj, ok := tree.(map[string]interface{}); ok {
+			if _, ok := obj["$schema"]; ok {
+				tree = without(obj, "$schema")
+			}
+		}
+
+		var err error
+		if tree, err = l.interpolate(tree); err != nil {
+			return fmt.Errorf("invalid config file %s. %v", strings.Join(names, " + "), err)
+		}
+		if l.schema != nil {
+			if errs := validateSchema(l.schema, tree, ""); len(errs) > 0 {
+				return fmt.Errorf("invalid config file %s. %v", strings.Join(names, " + "), schemaError(errs))
+			}
+		}
+		if err := l.applyTree(dst, tree, strings.Join(names, " + ")); err != nil {
+			return err
+		}
+	}
+	for _, path := range dotenvs {
+		bb, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file. %v", err)
+		}
+		if err := l.loadDotenv(dst, path, bb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decoder returns the decoder of a config file, detected by the extension of path:
+// .json, .yaml or .yml, .toml and .env, which has no decoder. Files without an extension are read as JSON.
+func decoder(path string) (func([]byte) (interface{}, error), error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json", "":
+		return decodeJSON, nil
+	case ".yaml", ".yml":
+		return decodeYAML, nil
+	case ".toml":
+		return decodeTOML, nil
+	case ".env": // matched by env tags, see loadDotenv
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported config file format %s of %s", ext, path)
+	}
+}
+
+// applyTree applies the decoded config files named name.
+func (l *loader) applyTree(dst reflect.Value, tree interface{}, name string) error {
+	l.unknown = nil
+	if err := l.apply(dst, tree, "", true); err != nil {
+		return fmt.Errorf("invalid config file %s. %v", name, err)
+	}
+	if len(l.unknown) > 0 {
+		return fmt.Errorf("invalid config file %s. %v", name, unknownKeysError(l.unknown))
+	}
+	return nil
+}
+
+// without returns a copy of obj without key.
+func without(obj map[string]interface{}, key string) map[string]interface{} {
+	c := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		if k != key {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+// merge deep merges the overlay config file tree into base:
+//   - objects are merged key by key
+//   - null removes the key, so the field keeps the value it has without the files, e.g. its default
+//   - anything else, arrays included, replaces the base value
+func merge(base, overlay interface{}) interface{} {
+	bobj, ok := base.(map[string]interface{})
+	oobj, ook := overlay.(map[string]interface{})
+	if !ok || !ook {
+		return overlay
+	}
+
+	merged := make(map[string]interface{}, len(bobj)+len(oobj))
+	for k, v := range bobj {
+		merged[k] = v
+	}
+	for k, v := range oobj {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		if b, ok := merged[k]; ok {
+			merged[k] = merge(b, v)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// apply sets v from a decoded config file node. path is the field path of v, used in the report and errors.
+// Fields are only reported if report is true, which is not the case for slice and map elements.
+func (l *loader) apply(v reflect.Value, node interface{}, path string, report bool) error {
+	if node == nil {
+		v.Set(reflect.Zero(v.Type()))
+		if report {
+			l.markFile(v, path)
+		}
+		return nil
+	}
+
+	// text types, e.g. time.Duration or url.URL, are written as strings even if they are structs or slices.
+	if str, ok := node.(string); ok && (isText(v.Type()) || v.Type() == durationType) {
+		if err := setString(v, str); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if report {
+			l.report[path] = SourceFile
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return typeError(path, "an object", node)
+		}
+		t := v.Type()
+		var known []string
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			name, skip := jsonName(sf)
+			if skip {
+				continue
+			}
+			known = append(known, name)
+			child, ok := obj[name]
+			if !ok {
+				continue
+			}
+			if err := l.apply(v.Field(i), child, join(path, sf.Name), report); err != nil {
+				return err
+			}
+		}
+		if l.strict {
+			l.unknown = append(l.unknown, unknownKeys(path, obj, known)...)
+		}
+		return nil
+
+	case reflect.Slice:
+		arr, ok := node.([]interface{})
+		if !ok {
+			return typeError(path, "an array", node)
+		}
+		s := reflect.MakeSlice(v.Type(), len(arr), len(arr))
+		for i, item := range arr {
+			if err := l.apply(s.Index(i), item, fmt.Sprintf("%s[%d]", path, i), false); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		if report {
+			l.report[path] = SourceFile
+		}
+		return nil
+
+	case reflect.Map:
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return typeError(path, "an object", node)
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: unsupported map key type %s", path, v.Type().Key())
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(obj))
+		for k, item := range obj {
+			ev := reflect.New(v.Type().Elem()).Elem()
+			if err := l.apply(ev, item, index(path, k), false); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), ev)
+		}
+		v.Set(m)
+		if report {
+			l.report[path] = SourceFile
+		}
+		return nil
+	}
+
+	if err := setScalar(v, node); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if report {
+		l.report[path] = SourceFile
+	}
+	return nil
+}
+
+// setScalar sets v from a decoded JSON string, bool or number.
+func setScalar(v reflect.Value, node interface{}) error {
+	switch n := node.(type) {
+	case string:
+		return setString(v, n)
+	case bool:
+		if v.Kind() != reflect.Bool {
+			return fmt.Errorf("expected %s, got a boolean", v.Type())
+		}
+		v.SetBool(n)
+		return nil
+	case json.Number:
+		if v.Type() == durationType {
+			return fmt.Errorf("expected a duration string like \"1m30s\", got a number")
+		}
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return setString(v, n.String())
+		}
+		return fmt.Errorf("expected %s, got a number", v.Type())
+	}
+	return fmt.Errorf("expected %s, got %T", v.Type(), node)
+}
+
+// markFile reports the field at path, and every leaf under it, as set from a file.
+func (l *loader) markFile(v reflect.Value, path string) {
+	if v.Kind() == reflect.Struct {
+		for _, f := range collect(v, path+".") {
+			l.report[f.path] = SourceFile
+		}
+		return
+	}
+	l.report[path] = SourceFile
+}
+
+func typeError(path, expected string, node interface{}) error {
+	got := describe(node)
+	if path == "" {
+		return fmt.Errorf("expected %s, got %s", expected, got)
+	}
+	return fmt.Errorf("%s: expected %s, got %s", path, expected, got)
+}
+
+// describe returns the type of a decoded config file node, e.g. "an object".
+func describe(node interface{}) string {
+	switch node.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "a number"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return "a value"
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
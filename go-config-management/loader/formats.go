@@ -0,0 +1,192 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// A decoded config file is a tree of map[string]interface{}, []interface{}, string, bool,
+// json.Number and nil, whatever its format, so every format is applied to the config the same way.
+
+// syntaxError is a parse error of a config file with its position. Column is 0 if the parser doesn't report it.
+type syntaxError struct {
+	Line, Column int
+	Msg          string
+}
+
+func (e syntaxError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// position converts a byte offset of bb to a 1-based line and column.
+func position(bb []byte, offset int) (line, column int) {
+	if offset > len(bb) {
+		offset = len(bb)
+	}
+	before := bb[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = offset - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+func decodeJSON(bb []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(bb))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		var serr *json.SyntaxError
+		switch {
+		case errors.As(err, &serr):
+			// Offset is right after the invalid character.
+			line, col := position(bb, int(serr.Offset)-1)
+			return nil, syntaxError{Line: line, Column: col, Msg: serr.Error()}
+		case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+			line, col := position(bb, len(bb))
+			return nil, syntaxError{Line: line, Column: col, Msg: "unexpected end of file"}
+		}
+		return nil, err
+	}
+
+	// the decoder stops after the first value, so anything but whitespace after it is reported here.
+	if rest := bytes.TrimLeft(bb[dec.InputOffset():], " \t\r\n"); len(rest) > 0 {
+		line, col := position(bb, len(bb)-len(rest))
+		r, _ := utf8.DecodeRune(rest)
+		return nil, syntaxError{Line: line, Column: col, Msg: fmt.Sprintf("invalid character %q after top-level value", r)}
+	}
+	return tree, nil
+}
+
+var yamlLineRe = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func decodeYAML(bb []byte) (interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bb, &doc); err != nil {
+		// yaml only reports the line of syntax errors.
+		if m := yamlLineRe.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, syntaxError{Line: line, Msg: m[2]}
+		}
+		return nil, err
+	}
+	if len(doc.Content) == 0 { // empty file
+		return map[string]interface{}{}, nil
+	}
+	return yamlTree(doc.Content[0])
+}
+
+func yamlTree(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return yamlTree(n.Alias)
+	case yaml.MappingNode:
+		obj := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v, err := yamlTree(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			obj[n.Content[i].Value] = v
+		}
+		return obj, nil
+	case yaml.SequenceNode:
+		arr := make([]interface{}, len(n.Content))
+		for i, c := range n.Content {
+			v, err := yamlTree(c)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = v
+		}
+		return arr, nil
+	}
+
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, syntaxError{Line: n.Line, Column: n.Column, Msg: err.Error()}
+		}
+		return b, nil
+	case "!!int":
+		// yaml ints may be written as 0x1f, 0o17 or 1_000.
+		var i int64
+		if err := n.Decode(&i); err != nil {
+			return json.Number(n.Value), nil
+		}
+		return json.Number(strconv.FormatInt(i, 10)), nil
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, syntaxError{Line: n.Line, Column: n.Column, Msg: err.Error()}
+		}
+		return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), nil
+	}
+	return n.Value, nil
+}
+
+var tomlMsgRe = regexp.MustCompile(`^toml: line \d+(?: \(last key "[^"]*"\))?: (.*)$`)
+
+func decodeTOML(bb []byte) (interface{}, error) {
+	var obj map[string]interface{}
+	if _, err := toml.Decode(string(bb), &obj); err != nil {
+		var perr toml.ParseError
+		if errors.As(err, &perr) {
+			line, col := position(bb, perr.Position.Start)
+			msg := perr.Message
+			if m := tomlMsgRe.FindStringSubmatch(perr.Error()); msg == "" && m != nil {
+				msg = m[1]
+			}
+			return nil, syntaxError{Line: line, Column: col, Msg: msg}
+		}
+		return nil, err
+	}
+	return tomlTree(obj), nil
+}
+
+func tomlTree(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, c := range v {
+			obj[k] = tomlTree(c)
+		}
+		return obj
+	case []map[string]interface{}: // array of tables
+		arr := make([]interface{}, len(v))
+		for i, c := range v {
+			arr[i] = tomlTree(c)
+		}
+		return arr
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, c := range v {
+			arr[i] = tomlTree(c)
+		}
+		return arr
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case string, bool:
+		return v
+	}
+	// local dates and times.
+	return fmt.Sprint(v)
+}
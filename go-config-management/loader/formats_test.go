@@ -0,0 +1,153 @@
+package loader_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"code.com/loader"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoad_Formats(t *testing.T) {
+	expected := config{
+		DBHost: "filehost",
+		DBPort: "5432",
+		DBUser: "fileuser",
+		Cron: cronConfig{
+			Schedule:    "30 0 * * *",
+			Disabled:    true,
+			NotifyEmail: []string{"jdoe@gmail.com"},
+		},
+	}
+
+	for _, file := range []string{"config.json", "config.yaml", "config.toml", "config.env"} {
+		t.Run(file, func(t *testing.T) {
+			var c config
+			report, err := loader.Load(&c, loader.WithFile(filepath.Join("testdata", file)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, c); diff != "" {
+				t.Errorf("configs are different (-want +got):\n%s", diff)
+			}
+			if report["Cron.Schedule"] != loader.SourceFile {
+				t.Errorf("Expected Cron.Schedule to be reported as file. Got %s", report["Cron.Schedule"])
+			}
+		})
+	}
+}
+
+func TestLoad_TypedFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "Port: 0x1F90\nRatio: 0.5\nTimeout: 5s\nPorts: [80, 443]\nWeights: {a: 1}\n",
+		"config.toml": "Port = 8080\nRatio = 0.5\nTimeout = \"5s\"\nPorts = [80, 443]\n[Weights]\na = 1\n",
+		"config.env":  "PORT=8080\nRATIO=0.5\nTIMEOUT=5s\nPORTS=80,443\nWEIGHTS=a=1\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var c typedConfig
+			if _, err := loader.Load(&c, loader.WithFile(path)); err != nil {
+				t.Fatal(err)
+			}
+			if c.Port != 8080 || c.Ratio != 0.5 || c.Timeout.String() != "5s" || len(c.Ports) != 2 || c.Weights["a"] != 1 {
+				t.Errorf("unexpected config %+v", c)
+			}
+		})
+	}
+}
+
+func TestLoad_SyntaxErrors(t *testing.T) {
+	testCases := []struct {
+		name, content string
+		expectedErr   string
+	}{
+		{
+			name:        "config.json",
+			content:     "{\n  \"dbHost\": \"filehost\",\n  \"dbUser\" \"fileuser\"\n}",
+			expectedErr: "line 3, column 12: invalid character '\"' after object key",
+		},
+		{
+			name:        "config.json",
+			content:     "{\n  \"dbHost\": \"filehost\",\n",
+			expectedErr: "line 3, column 1: unexpected end of file",
+		},
+		{
+			name:        "config.json",
+			content:     "{\n  \"dbHost\": \"filehost\"\n} garbage\n",
+			expectedErr: "line 3, column 3: invalid character 'g' after top-level value",
+		},
+		{
+			name:        "config.json",
+			content:     "{\"dbHost\": \"filehost\"}\n{\"dbUser\": \"fileuser\"}\n",
+			expectedErr: "line 2, column 1: invalid character '{' after top-level value",
+		},
+		{
+			name:        "config.yaml",
+			content:     "dbHost: filehost\ncron:\n  schedule: \"30 0 * * *\n",
+			expectedErr: "line 3: found unexpected end of stream",
+		},
+		{
+			name:        "config.toml",
+			content:     "dbHost = \"filehost\"\ndbUser = fileuser\n",
+			expectedErr: "line 2, column 10: expected value but found \"fileuser\" instead",
+		},
+		{
+			name:        "config.env",
+			content:     "DB_HOST=filehost\nDB_USER\n",
+			expectedErr: "line 2, column 1: expected KEY=value",
+		},
+		{
+			name:        "config.env",
+			content:     "DB_HOST=filehost\n  DB-USER=fileuser\n",
+			expectedErr: "line 2, column 5: invalid key \"DB-USER\"",
+		},
+		{
+			name:        "config.env",
+			content:     "DB_HOST=\"filehost\n",
+			expectedErr: "line 1, column 9: unterminated quoted value",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name+"/"+tC.expectedErr, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tC.name)
+			if err := ioutil.WriteFile(path, []byte(tC.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var c config
+			_, err := loader.Load(&c, loader.WithFile(path))
+			expectedErr := "failed to unmarshal config file " + path + ". " + tC.expectedErr
+			if err == nil || err.Error() != expectedErr {
+				t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_DotenvInvalidValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := ioutil.WriteFile(path, []byte("DEBUG=true\nPORT=abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var c typedConfig
+	_, err := loader.Load(&c, loader.WithFile(path))
+	expectedErr := "invalid config file " + path + `. line 2: PORT: invalid integer "abc"`
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+}
+
+func TestLoad_UnsupportedFormat(t *testing.T) {
+	var c config
+	_, err := loader.Load(&c, loader.WithFile("testdata/config.ini"))
+	if err == nil || err.Error() != "unsupported config file format .ini of testdata/config.ini" {
+		t.Errorf("Expected an unsupported format error. Got %v", err)
+	}
+}
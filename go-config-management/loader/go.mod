@@ -0,0 +1,9 @@
+module code.com/loader
+
+go 1.17
+
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/google/go-cmp v0.5.8
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,66 @@
+package loader
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Reload is a config reload that changed some fields.
+type Reload struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Changes []Change  `json:"changes"`
+}
+
+// History keeps the last reloads of a config in memory, as an audit trail of its changes.
+// It's safe for concurrent use.
+type History struct {
+	size int
+
+	mu      sync.Mutex
+	reloads []Reload
+}
+
+// NewHistory initiates a history that keeps the last size reloads.
+func NewHistory(size int) *History {
+	return &History{size: size}
+}
+
+// Record adds a reload of source with changes to the history. Reloads without changes are ignored.
+func (h *History) Record(source string, changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reloads = append(h.reloads, Reload{Time: time.Now(), Source: source, Changes: changes})
+	if len(h.reloads) > h.size {
+		h.reloads = h.reloads[len(h.reloads)-h.size:]
+	}
+}
+
+// Reloads returns the recorded reloads, oldest first.
+func (h *History) Reloads() []Reload {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Reload{}, h.reloads...)
+}
+
+// Handler serves the recorded reloads as JSON.
+func (h *History) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := struct {
+			Reloads []Reload `json:"reloads"`
+		}{
+			Reloads: h.Reloads(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
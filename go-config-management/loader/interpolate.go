@@ -0,0 +1,258 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var varName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// interpolator expands the variables in the string values of a decoded config file tree:
+//
+//	${VAR}              the env var VAR, read through the lookup of WithEnv
+//	${VAR:-fallback}    fallback if VAR is unset or empty. fallback may contain variables itself
+//	${.path.to.key}     the value of another key of the files, e.g. ${.smtp.from} or ${.hosts[0]}
+//	$$                  a literal $
+//
+// A value that is a single key reference keeps the type of the referenced value, e.g. a number or an array.
+// Undefined variables without a fallback are an error unless WithStrict(false) is given, in which case
+// they expand to "".
+type interpolator struct {
+	l        *loader
+	root     interface{}
+	resolved map[string]interface{} // expanded values by key path
+	visiting []string               // key paths being expanded, to detect reference cycles
+}
+
+// interpolate returns tree with every variable expanded.
+func (l *loader) interpolate(tree interface{}) (interface{}, error) {
+	ip := &interpolator{l: l, root: tree, resolved: map[string]interface{}{}}
+	return ip.walk(tree, "")
+}
+
+// expandString expands the env vars of a value that isn't part of a tree, e.g. a value of a .env file.
+func (l *loader) expandString(s string) (string, error) {
+	ip := &interpolator{l: l, resolved: map[string]interface{}{}}
+	v, err := ip.expand(s, "")
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// walk expands the variables of node, found at the key path path.
+func (ip *interpolator) walk(node interface{}, path string) (interface{}, error) {
+	if v, ok := ip.resolved[path]; ok {
+		return v, nil
+	}
+	for i, p := range ip.visiting {
+		if p == path {
+			cycle := append(append([]string{}, ip.visiting[i:]...), path)
+			return nil, fmt.Errorf("reference cycle %s", strings.Join(cycle, " -> "))
+		}
+	}
+	ip.visiting = append(ip.visiting, path)
+	defer func() { ip.visiting = ip.visiting[:len(ip.visiting)-1] }()
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch n := node.(type) {
+	case string:
+		v, err = ip.expand(n, path)
+	case map[string]interface{}:
+		// sorted, so the same errors are reported every time.
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		obj := make(map[string]interface{}, len(n))
+		for _, k := range keys {
+			if obj[k], err = ip.walk(n[k], join(path, k)); err != nil {
+				return nil, err
+			}
+		}
+		v = obj
+	case []interface{}:
+		arr := make([]interface{}, len(n))
+		for i, child := range n {
+			if arr[i], err = ip.walk(child, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return nil, err
+			}
+		}
+		v = arr
+	default:
+		v = node
+	}
+	if err != nil {
+		return nil, err
+	}
+	ip.resolved[path] = v
+	return v, nil
+}
+
+// expand expands the variables of the string s, the value of the key path path.
+func (ip *interpolator) expand(s, path string) (interface{}, error) {
+	// a single key reference keeps the type of the referenced value.
+	if strings.HasPrefix(s, "${.") && closingBrace(s, 1) == len(s)-1 {
+		return ip.variable(s[2:len(s)-1], path)
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := closingBrace(s, i+1)
+			if end < 0 {
+				return nil, errorAt(path, fmt.Errorf("unterminated variable %q", s[i:]))
+			}
+			v, err := ip.variable(s[i+2:end], path)
+			if err != nil {
+				return nil, err
+			}
+			str, err := scalarString(v)
+			if err != nil {
+				return nil, errorAt(path, fmt.Errorf("%s: %v", s[i:end+1], err))
+			}
+			b.WriteString(str)
+			i = end
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// variable returns the value of the variable expression expr, the text between ${ and }.
+func (ip *interpolator) variable(expr, path string) (interface{}, error) {
+	name, fallback, hasFallback := expr, "", false
+	if i := strings.Index(expr, ":-"); i >= 0 {
+		name, fallback, hasFallback = expr[:i], expr[i+2:], true
+	}
+
+	var (
+		v       interface{}
+		defined bool
+	)
+	if strings.HasPrefix(name, ".") {
+		if ip.root == nil {
+			return nil, errorAt(path, fmt.Errorf("key references like ${%s} are only supported in JSON, YAML and TOML files", name))
+		}
+		node, ok := lookupKey(ip.root, name[1:])
+		if ok && node != nil {
+			var err error
+			if v, err = ip.walk(node, name[1:]); err != nil {
+				return nil, err
+			}
+			defined = true
+		}
+	} else {
+		if !varName.MatchString(name) {
+			return nil, errorAt(path, fmt.Errorf("invalid variable name %q", name))
+		}
+		if ip.l.env != nil {
+			value, envName, ok, err := ip.l.lookupEnv(name)
+			if err != nil {
+				return nil, errorAt(path, fmt.Errorf("%s: %v", envName, err))
+			}
+			v, defined = value, ok
+		}
+	}
+
+	switch {
+	case defined:
+		return v, nil
+	case hasFallback:
+		return ip.expand(fallback, path)
+	case ip.l.strict && strings.HasPrefix(name, "."):
+		return nil, errorAt(path, fmt.Errorf("undefined key %s", name[1:]))
+	case ip.l.strict:
+		return nil, errorAt(path, fmt.Errorf("undefined variable %s", name))
+	}
+	return "", nil
+}
+
+// closingBrace returns the index of the } closing the { at s[open], skipping nested variables, or -1.
+func closingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// lookupKey returns the node at the key path path of tree, e.g. "smtp.from" or "hosts[0]".
+func lookupKey(tree interface{}, path string) (interface{}, bool) {
+	node := tree
+	for _, part := range strings.Split(path, ".") {
+		key, indexes := part, ""
+		if i := strings.Index(part, "["); i >= 0 {
+			key, indexes = part[:i], part[i:]
+		}
+
+		if key != "" {
+			obj, ok := node.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if node, ok = obj[key]; !ok {
+				return nil, false
+			}
+		}
+		for indexes != "" {
+			end := strings.Index(indexes, "]")
+			if !strings.HasPrefix(indexes, "[") || end < 0 {
+				return nil, false
+			}
+			i, err := strconv.Atoi(indexes[1:end])
+			arr, ok := node.([]interface{})
+			if err != nil || !ok || i < 0 || i >= len(arr) {
+				return nil, false
+			}
+			node, indexes = arr[i], indexes[end+1:]
+		}
+	}
+	return node, true
+}
+
+// scalarString returns the string form of a decoded config file value embedded in a string.
+func scalarString(v interface{}) (string, error) {
+	switch n := v.(type) {
+	case string:
+		return n, nil
+	case bool:
+		return strconv.FormatBool(n), nil
+	case json.Number:
+		return n.String(), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("objects and arrays can only be referenced as the whole value")
+}
+
+func errorAt(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %v", path, err)
+}
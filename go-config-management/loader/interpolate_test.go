@@ -0,0 +1,159 @@
+package loader_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"code.com/loader"
+	"github.com/google/go-cmp/cmp"
+)
+
+type interpolatedConfig struct {
+	SMTPHost string   `json:"smtpHost"`
+	SMTPPort int      `json:"smtpPort"`
+	SMTPAddr string   `json:"smtpAddr"`
+	From     string   `json:"from"`
+	Emails   []string `json:"emails"`
+	Alerts   []string `json:"alerts"`
+	Price    string   `json:"price"`
+}
+
+func TestLoad_Interpolation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+smtpHost: ${SMTP_HOST:-localhost}
+smtpPort: ${SMTP_PORT}
+smtpAddr: ${.smtpHost}:${.smtpPort}
+from: cron@${MAIL_DOMAIN:-${SMTP_HOST:-localhost}}
+emails: ["${OPS_EMAIL}", "${.from}"]
+alerts: ${.emails}
+price: $$5 or $5
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var c interpolatedConfig
+	_, err := loader.Load(&c,
+		loader.WithFile(path),
+		loader.WithEnv(env(map[string]string{
+			"SMTP_PORT":   "2525",
+			"OPS_EMAIL":   "ops@code.com",
+			"MAIL_DOMAIN": "", // empty counts as unset
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := interpolatedConfig{
+		SMTPHost: "localhost",
+		SMTPPort: 2525,
+		SMTPAddr: "localhost:2525",
+		From:     "cron@localhost",
+		Emails:   []string{"ops@code.com", "cron@localhost"},
+		Alerts:   []string{"ops@code.com", "cron@localhost"},
+		Price:    "$5 or $5",
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("configs are different (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoad_InterpolationErrors(t *testing.T) {
+	dir := t.TempDir()
+	testCases := []struct {
+		desc        string
+		content     string
+		strict      bool
+		expectedErr string
+	}{
+		{
+			desc:        "undefined variable",
+			content:     `{"emails": ["${OPS_EMAIL}"]}`,
+			strict:      true,
+			expectedErr: "emails[0]: undefined variable OPS_EMAIL",
+		},
+		{
+			desc:        "undefined key",
+			content:     `{"from": "${.smtp.from}"}`,
+			strict:      true,
+			expectedErr: "from: undefined key smtp.from",
+		},
+		{
+			desc:    "undefined variables are empty when not strict",
+			content: `{"from": "cron@${MAIL_DOMAIN}"}`,
+		},
+		{
+			desc:        "reference cycle",
+			content:     `{"smtpHost": "${.smtpAddr}", "smtpAddr": "${.from}:25", "from": "cron@${.smtpHost}"}`,
+			strict:      true,
+			expectedErr: "reference cycle from -> smtpHost -> smtpAddr -> from",
+		},
+		{
+			desc:        "self reference",
+			content:     `{"emails": ["${.emails}"]}`,
+			strict:      true,
+			expectedErr: "reference cycle emails -> emails[0] -> emails",
+		},
+		{
+			desc:        "array embedded in a string",
+			content:     `{"emails": ["ops@code.com"], "from": "${.emails} team"}`,
+			strict:      true,
+			expectedErr: "from: ${.emails}: objects and arrays can only be referenced as the whole value",
+		},
+		{
+			desc:        "unterminated variable",
+			content:     `{"from": "${OPS_EMAIL"}`,
+			strict:      true,
+			expectedErr: `from: unterminated variable "${OPS_EMAIL"`,
+		},
+		{
+			desc:        "invalid variable name",
+			content:     `{"from": "${OPS EMAIL}"}`,
+			strict:      true,
+			expectedErr: `from: invalid variable name "OPS EMAIL"`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			path := filepath.Join(dir, "config.json")
+			if err := ioutil.WriteFile(path, []byte(tC.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var c interpolatedConfig
+			_, err := loader.Load(&c,
+				loader.WithFile(path),
+				loader.WithEnv(env(nil)),
+				loader.WithStrict(tC.strict),
+			)
+			if tC.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error. Got %v", err)
+				}
+				return
+			}
+			expectedErr := "invalid config file " + path + ". " + tC.expectedErr
+			if err == nil || err.Error() != expectedErr {
+				t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_InterpolationDotenv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.env")
+	if err := ioutil.WriteFile(path, []byte("DB_HOST=${PGHOST:-db}.internal\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var c config
+	if _, err := loader.Load(&c, loader.WithFile(path), loader.WithEnv(env(nil))); err != nil {
+		t.Fatal(err)
+	}
+	if c.DBHost != "db.internal" {
+		t.Errorf("Expected DBHost to be db.internal. Got %q", c.DBHost)
+	}
+}
@@ -0,0 +1,348 @@
+// Package loader populates config structs from defaults, config files, environment variables and flags
+// based on struct tags:
+//
+//	type Config struct {
+//		DBHost string `env:"DB_HOST" flag:"db-host" default:"localhost" json:"dbHost" usage:"database host."`
+//	}
+//
+// Sources are applied in this order, each one overriding the previous ones:
+//
+//  1. default tag
+//  2. config files, in the order they are given, matched by the json tag. JSON, YAML, TOML and .env files are supported.
+//     Keys that don't match any field are rejected unless WithStrict(false) is given. Later files, e.g. the
+//     overlays of profiles, are deep merged into the earlier ones: objects are merged key by key, null removes
+//     a key and anything else, arrays included, is replaced. Then ${VAR}, ${VAR:-fallback} and ${.key}
+//     variables of string values are expanded, see interpolator
+//  3. environment variables, matched by the env tag. Empty variables count as unset.
+//     If KEY is unset, it is read from the file KEY_FILE points to
+//  4. flags, matched by the flag tag. Only the flags that are passed count as set
+//
+// Values like secret://db/password are then replaced with the secret they point to if WithSecrets is given.
+//
+// Besides strings, fields can be ints, uints, floats, bools, time.Duration, url.URL, net.IP, pointers to them,
+// slices of them written as comma separated lists, maps of them written as k=v,k2=v2 and any type
+// implementing encoding.TextUnmarshaler.
+//
+// Nested structs are walked recursively. A struct field is only treated as a single value
+// if it has an env, flag or default tag itself.
+//
+// After loading, fields are checked against their required and validate tags:
+//
+//	DBPassword string `env:"DB_PASSWORD" required:"prod"`
+//	DBPort     int    `env:"DB_PORT" default:"5432" validate:"port"`
+//	SSLMode    string `env:"DB_SSLMODE" validate:"oneof=disable require verify-full"`
+//
+// required:"true" fields must be set by a file, env var or flag, defaults don't count.
+// required:"staging,prod" fields are only required when the profile given to WithProfile is one of them.
+// Required fields of a nested struct are only checked if the struct is required itself or any of its fields is set.
+// See checkRules for the validate rules. Invalid values and failed checks of every field are
+// returned together as Errors.
+package loader
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Source is where the value of a field came from.
+type Source string
+
+// Sources of field values.
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Report maps field paths, e.g. "DB.Host", to the source their value came from. Maps and slices are reported
+// as a whole, e.g. "CronConfigs", not by entry like CronConfigs[inventory].Schedule.
+// Fields that kept their zero value are not in the report.
+type Report map[string]Source
+
+// Option adds a source to Load.
+type Option func(*loader)
+
+// WithEnv reads the fields with an env tag through lookup, e.g. os.LookupEnv.
+// The ${VAR} variables of config files are looked up with it too, see interpolator.
+func WithEnv(lookup func(key string) (string, bool)) Option {
+	return func(l *loader) { l.env = lookup }
+}
+
+// WithFlags registers a flag on fs for every field with a flag tag and parses args with it.
+// The usage of fs, printed for -help, lists every flag with its env var and default, and the env vars
+// that have no flag. Parsing returns flag.ErrHelp for -help if fs was created with flag.ContinueOnError.
+func WithFlags(fs *flag.FlagSet, args []string) Option {
+	return func(l *loader) {
+		l.fs = fs
+		l.args = args
+	}
+}
+
+// WithStrict sets whether keys of config files that don't match any field and undefined variables
+// of config files are rejected, which is the default.
+// The error suggests the closest known key, so typos don't silently leave fields unset.
+func WithStrict(strict bool) Option {
+	return func(l *loader) { l.strict = strict }
+}
+
+// WithProfile sets the profile, e.g. "prod", fields with a required tag listing profiles are required in.
+func WithProfile(profile string) Option {
+	return func(l *loader) { l.profile = profile }
+}
+
+// WithFile reads a config file. The format is detected by the extension: .json, .yaml or .yml, .toml and .env.
+// Keys are matched to fields by their json tag, or their name if they don't have one, whatever the format.
+// Keys of .env files are matched to the env tag of fields instead, see loadDotenv.
+func WithFile(path string) Option {
+	return func(l *loader) { l.files = append(l.files, file{path: path}) }
+}
+
+// WithOverlay reads the config file path if it exists, e.g. the overlay of a profile. See OverlayPath.
+func WithOverlay(path string) Option {
+	return func(l *loader) {
+		if path != "" {
+			l.files = append(l.files, file{path: path, optional: true})
+		}
+	}
+}
+
+// OverlayPath returns the path of the overlay of the config file path for profile, e.g.
+// cron_config.prod.json for cron_config.json and prod. It returns "" if profile is empty.
+func OverlayPath(path, profile string) string {
+	if profile == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// WithSchema validates config files against the JSON Schema s, e.g. the one Schema generates, after
+// they are merged and their variables expanded. Every violation is reported, not just the first one.
+func WithSchema(s map[string]interface{}) Option {
+	return func(l *loader) { l.schema = s }
+}
+
+type file struct {
+	path     string
+	optional bool
+}
+
+type loader struct {
+	env     func(string) (string, bool)
+	fs      *flag.FlagSet
+	args    []string
+	files   []file
+	profile string
+	strict  bool
+	schema  map[string]interface{}
+	secrets SecretProvider
+
+	report  Report
+	errs    Errors
+	failed  map[string]bool
+	unknown []string // unknown keys of the file being loaded
+}
+
+// field is a leaf field of the config struct.
+type field struct {
+	path  string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// Load populates dst, which must be a pointer to a struct, from the given sources.
+// dst may also point to a map with string keys, e.g. jobs by name, which is only read from config files.
+func Load(dst interface{}, opts ...Option) (Report, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || !isRoot(rv.Elem().Type()) {
+		return nil, errors.New("loader: dst must be a non-nil pointer to a struct or a map with string keys")
+	}
+
+	l := &loader{report: Report{}, failed: map[string]bool{}, strict: true}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	var fields []field
+	if rv.Elem().Kind() == reflect.Struct {
+		fields = collect(rv.Elem(), "")
+	}
+
+	for _, f := range fields {
+		def, ok := f.tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		if err := setString(f.value, def); err != nil {
+			return nil, fmt.Errorf("invalid default of %s. %v", f.path, err)
+		}
+		l.report[f.path] = SourceDefault
+	}
+
+	if err := l.loadFiles(rv.Elem()); err != nil {
+		return nil, err
+	}
+
+	if l.env != nil {
+		for _, f := range fields {
+			key := f.tag.Get("env")
+			if key == "" {
+				continue
+			}
+			v, name, ok, err := l.lookupEnv(key)
+			if err != nil {
+				l.fail(f.path, name, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			if err := setString(f.value, v); err != nil {
+				l.fail(f.path, name, err)
+				continue
+			}
+			l.report[f.path] = SourceEnv
+		}
+	}
+
+	if l.fs != nil {
+		if err := l.loadFlags(fields); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.secrets != nil {
+		l.resolveSecrets(fields)
+	}
+
+	if rv.Elem().Kind() == reflect.Struct {
+		l.errs = append(l.errs, l.validate(rv.Elem(), "", "", false, true)...)
+	} else {
+		l.errs = append(l.errs, l.validateNested(rv.Elem(), "", "", false, true)...)
+	}
+	if len(l.errs) > 0 {
+		return l.report, l.errs
+	}
+	return l.report, nil
+}
+
+// fail records that the field at path couldn't be set from key.
+func (l *loader) fail(path, key string, err error) {
+	l.errs = append(l.errs, FieldError{Field: path, Key: key, Msg: err.Error()})
+	l.failed[path] = true
+}
+
+func isRoot(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct || (t.Kind() == reflect.Map && t.Key().Kind() == reflect.String)
+}
+
+// index returns the path of the element key of the map at path.
+func index(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "[" + key + "]"
+}
+
+// collect returns the leaf fields of the struct v.
+func collect(v reflect.Value, prefix string) []field {
+	var ff []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+
+		f := field{path: prefix + sf.Name, value: v.Field(i), tag: sf.Tag}
+		if sf.Type.Kind() == reflect.Struct && !hasSourceTag(sf.Tag) && !isText(sf.Type) {
+			ff = append(ff, collect(f.value, f.path+".")...)
+			continue
+		}
+		ff = append(ff, f)
+	}
+	return ff
+}
+
+func hasSourceTag(tag reflect.StructTag) bool {
+	for _, key := range []string{"env", "flag", "default"} {
+		if _, ok := tag.Lookup(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// flagValue collects the raw value of a flag so it can be applied after parsing.
+type flagValue struct {
+	value    string
+	isBool   bool
+	isSecret bool
+}
+
+// String is used for the default in -help, so defaults of secrets are redacted.
+func (fv *flagValue) String() string {
+	if fv.isSecret {
+		return Secret(fv.value).String()
+	}
+	return fv.value
+}
+
+func (fv *flagValue) Set(s string) error { fv.value = s; return nil }
+
+// IsBoolFlag lets bool fields be set with -name instead of -name=true.
+func (fv *flagValue) IsBoolFlag() bool { return fv.isBool }
+
+func (l *loader) loadFlags(fields []field) error {
+	values := map[string]*flagValue{}
+	byName := map[string]field{}
+	for _, f := range fields {
+		name := f.tag.Get("flag")
+		if name == "" {
+			continue
+		}
+		fv := &flagValue{
+			value:    f.tag.Get("default"),
+			isBool:   f.value.Kind() == reflect.Bool,
+			isSecret: f.value.Type() == secretType,
+		}
+		l.fs.Var(fv, name, f.tag.Get("usage"))
+		values[name] = fv
+		byName[name] = f
+	}
+	l.fs.Usage = func() { usage(l.fs, fields) }
+
+	if err := l.fs.Parse(l.args); err != nil {
+		return err
+	}
+
+	l.fs.Visit(func(fl *flag.Flag) {
+		f, ok := byName[fl.Name]
+		if !ok {
+			return
+		}
+		if err := setString(f.value, values[fl.Name].value); err != nil {
+			l.fail(f.path, "-"+fl.Name, err)
+			return
+		}
+		l.report[f.path] = SourceFlag
+	})
+	return nil
+}
+
+// jsonName returns the key of the field in config files and whether the field is skipped.
+func jsonName(sf reflect.StructField) (string, bool) {
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, false
+	}
+	return sf.Name, false
+}
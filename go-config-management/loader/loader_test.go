@@ -0,0 +1,164 @@
+package loader_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"code.com/loader"
+	"github.com/google/go-cmp/cmp"
+)
+
+type cronConfig struct {
+	Schedule    string   `json:"schedule"`
+	Disabled    bool     `json:"disabled"`
+	NotifyEmail []string `json:"notifyEmail"`
+}
+
+type config struct {
+	DBHost     string     `env:"DB_HOST" flag:"db-host" default:"localhost" json:"dbHost"`
+	DBPort     string     `env:"DB_PORT" flag:"db-port" default:"5432" json:"dbPort"`
+	DBUser     string     `env:"DB_USER" flag:"db-user" default:"postgres" json:"dbUser"`
+	DBPassword string     `env:"DB_PASSWORD" flag:"db-password" json:"dbPassword"`
+	Cron       cronConfig `json:"cron"`
+	Ignored    string     `json:"-"`
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	var c config
+	report, err := loader.Load(&c,
+		loader.WithFile("testdata/config.json"),
+		loader.WithEnv(env(map[string]string{
+			"DB_USER":     "envuser",
+			"DB_PASSWORD": "envpass",
+			"DB_PORT":     "", // empty counts as unset
+		})),
+		loader.WithFlags(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-db-password=flagpass"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := config{
+		DBHost:     "filehost",
+		DBPort:     "5432",
+		DBUser:     "envuser",
+		DBPassword: "flagpass",
+		Cron: cronConfig{
+			Schedule:    "30 0 * * *",
+			Disabled:    true,
+			NotifyEmail: []string{"jdoe@gmail.com"},
+		},
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("configs are different (-want +got):\n%s", diff)
+	}
+
+	expectedReport := loader.Report{
+		"DBHost":           loader.SourceFile,
+		"DBPort":           loader.SourceDefault,
+		"DBUser":           loader.SourceEnv,
+		"DBPassword":       loader.SourceFlag,
+		"Cron.Schedule":    loader.SourceFile,
+		"Cron.Disabled":    loader.SourceFile,
+		"Cron.NotifyEmail": loader.SourceFile,
+	}
+	if diff := cmp.Diff(expectedReport, report); diff != "" {
+		t.Errorf("reports are different (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	testCases := []struct {
+		desc        string
+		opts        []loader.Option
+		expectedErr string
+	}{
+		{
+			desc:        "missing file",
+			opts:        []loader.Option{loader.WithFile(filepath.Join(dir, "nope.json"))},
+			expectedErr: "failed to read config file. open " + filepath.Join(dir, "nope.json") + ": no such file or directory",
+		},
+		{
+			desc:        "wrong type",
+			opts:        []loader.Option{loader.WithFile(write("type.json", `{"cron": {"disabled": "yes"}}`))},
+			expectedErr: "invalid config file " + filepath.Join(dir, "type.json") + ". Cron.Disabled: invalid boolean \"yes\"",
+		},
+		{
+			desc:        "wrong shape",
+			opts:        []loader.Option{loader.WithFile(write("shape.json", `{"cron": ["30 0 * * *"]}`))},
+			expectedErr: "invalid config file " + filepath.Join(dir, "shape.json") + ". Cron: expected an object, got an array",
+		},
+		{
+			desc:        "unknown flag",
+			opts:        []loader.Option{loader.WithFlags(quietFlagSet(), []string{"-nope"})},
+			expectedErr: "flag provided but not defined: -nope",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var c config
+			_, err := loader.Load(&c, tC.opts...)
+			if err == nil || err.Error() != tC.expectedErr {
+				t.Errorf("Expected error to be %q. Got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
+
+func quietFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return fs
+}
+
+func TestLoad_InvalidDst(t *testing.T) {
+	var c config
+	if _, err := loader.Load(c); err == nil {
+		t.Error("Expected a non pointer dst to fail")
+	}
+}
+
+func TestLoad_Map(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	content := `{"inventoryCron": {"schedule": "30 0 * * *"}, "reportsCron": {"disabled": true}}`
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type job struct {
+		Schedule string `json:"schedule" required:"true"`
+		Disabled bool   `json:"disabled"`
+	}
+	var jobs map[string]job
+	_, err := loader.Load(&jobs, loader.WithFile(path))
+
+	expectedErr := "reportsCron.schedule: is required"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+	expected := map[string]job{
+		"inventoryCron": {Schedule: "30 0 * * *"},
+		"reportsCron":   {Disabled: true},
+	}
+	if diff := cmp.Diff(expected, jobs); diff != "" {
+		t.Errorf("jobs are different (-want +got):\n%s", diff)
+	}
+}
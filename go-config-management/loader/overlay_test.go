@@ -0,0 +1,67 @@
+package loader_test
+
+import (
+	"testing"
+
+	"code.com/loader"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoad_Overlay(t *testing.T) {
+	var c config
+	report, err := loader.Load(&c,
+		loader.WithFile("testdata/overlay.json"),
+		loader.WithOverlay("testdata/overlay.prod.yaml"),
+		loader.WithOverlay("testdata/overlay.staging.json"), // doesn't exist
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := config{
+		DBHost: "prodhost",
+		DBPort: "5432",
+		DBUser: "postgres", // null in the overlay, back to the default
+		Cron: cronConfig{
+			Schedule:    "30 0 * * *",
+			Disabled:    false,
+			NotifyEmail: []string{"oncall@gmail.com"}, // arrays are replaced
+		},
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("configs are different (-want +got):\n%s", diff)
+	}
+	if report["DBUser"] != loader.SourceDefault {
+		t.Errorf("Expected DBUser to be reported as default. Got %s", report["DBUser"])
+	}
+}
+
+func TestLoad_OverlayErrors(t *testing.T) {
+	var c config
+	_, err := loader.Load(&c,
+		loader.WithFile("testdata/overlay.json"),
+		loader.WithOverlay("testdata/config.yaml"),
+		loader.WithOverlay("testdata/validate.json"),
+	)
+	expectedErr := `invalid config file testdata/overlay.json + testdata/config.yaml + testdata/validate.json. 2 unknown keys:` +
+		"\n\tunknown key \"job\"\n\tunknown key \"jobs\""
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+}
+
+func TestOverlayPath(t *testing.T) {
+	testCases := []struct {
+		path, profile, expected string
+	}{
+		{path: "cron_config.json", profile: "prod", expected: "cron_config.prod.json"},
+		{path: "config/app.yaml", profile: "staging", expected: "config/app.staging.yaml"},
+		{path: "config", profile: "local", expected: "config.local"},
+		{path: "cron_config.json", profile: "", expected: ""},
+	}
+	for _, tC := range testCases {
+		if got := loader.OverlayPath(tC.path, tC.profile); got != tC.expected {
+			t.Errorf("Expected OverlayPath(%q, %q) to be %q. Got %q", tC.path, tC.profile, tC.expected, got)
+		}
+	}
+}
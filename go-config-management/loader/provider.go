@@ -0,0 +1,76 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SecretScheme prefixes the values that are references to a secret, e.g. secret://db/password.
+const SecretScheme = "secret://"
+
+// SecretProvider resolves secret references. path is the reference without the scheme, e.g. db/password.
+type SecretProvider interface {
+	Secret(ctx context.Context, path string) (string, error)
+}
+
+// WithSecrets resolves the string and Secret fields whose value, from any source, is a reference
+// like secret://db/password through p after every source is applied.
+func WithSecrets(p SecretProvider) Option {
+	return func(l *loader) { l.secrets = p }
+}
+
+// Env vars of the secret store, see SecretsFromEnv.
+const (
+	EnvVaultAddr   = "VAULT_ADDR"
+	EnvVaultToken  = "VAULT_TOKEN"
+	EnvVaultMount  = "VAULT_MOUNT"
+	EnvSecretsFile = "SECRETS_FILE"
+	EnvSecretsKey  = "SECRETS_KEY"
+)
+
+// SecretsFromEnv returns the secret provider configured by env vars looked up with lookup, or nil if none is:
+//   - VAULT_ADDR and VAULT_TOKEN, and optionally VAULT_MOUNT, read secrets from a Vault KV v2 engine, see Vault
+//   - SECRETS_FILE and SECRETS_KEY read them from a file encrypted with the key, see EncryptedFile
+func SecretsFromEnv(lookup func(string) (string, bool)) (SecretProvider, error) {
+	get := func(key string) string {
+		v, _ := lookup(key)
+		return v
+	}
+
+	switch {
+	case get(EnvVaultAddr) != "":
+		if get(EnvVaultToken) == "" {
+			return nil, fmt.Errorf("VAULT_TOKEN is required with VAULT_ADDR")
+		}
+		return &Vault{Addr: get(EnvVaultAddr), Token: get(EnvVaultToken), Mount: get(EnvVaultMount)}, nil
+	case get(EnvSecretsFile) != "":
+		key, err := ParseSecretsKey(get(EnvSecretsKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SECRETS_KEY. %v", err)
+		}
+		f, err := OpenEncryptedFile(get(EnvSecretsFile), key)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return nil, nil
+}
+
+// resolveSecrets replaces the secret references of fields with the secrets they point to.
+func (l *loader) resolveSecrets(fields []field) {
+	for _, f := range fields {
+		if f.value.Kind() != reflect.String || !strings.HasPrefix(f.value.String(), SecretScheme) {
+			continue
+		}
+		ref := strings.TrimPrefix(f.value.String(), SecretScheme)
+		v, err := l.secrets.Secret(context.Background(), ref)
+		if err != nil {
+			l.fail(f.path, key(f.path, f.tag), fmt.Errorf("failed to resolve secret %s. %v", ref, err))
+			continue
+		}
+		f.value.SetString(v)
+	}
+}
@@ -0,0 +1,167 @@
+package loader_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"code.com/loader"
+	"github.com/google/go-cmp/cmp"
+)
+
+type providedConfig struct {
+	DBUser     string        `env:"DB_USER" flag:"db-user"`
+	DBPassword loader.Secret `env:"DB_PASSWORD" required:"true"`
+	APIKey     loader.Secret `env:"API_KEY"`
+}
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestLoad_EncryptedFileSecrets(t *testing.T) {
+	bb, err := loader.EncryptSecrets(map[string]string{"db/password": "correcthorse", "db/user": "app"}, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := ioutil.WriteFile(path, bb, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	secrets, err := loader.SecretsFromEnv(env(map[string]string{
+		"SECRETS_FILE": path,
+		"SECRETS_KEY":  base64.StdEncoding.EncodeToString(testKey),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c providedConfig
+	_, err = loader.Load(&c,
+		loader.WithEnv(env(map[string]string{"DB_PASSWORD": "secret://db/password", "API_KEY": "secret://api/key"})),
+		loader.WithFlags(quietFlagSet(), []string{"-db-user=secret://db/user"}),
+		loader.WithSecrets(secrets),
+	)
+
+	expectedErr := "API_KEY: failed to resolve secret api/key. secret not found"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+	expected := providedConfig{DBUser: "app", DBPassword: "correcthorse", APIKey: "secret://api/key"}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("configs are different (-want +got):\n%s", diff)
+	}
+}
+
+func TestOpenEncryptedFile_WrongKey(t *testing.T) {
+	bb, err := loader.EncryptSecrets(map[string]string{"db/password": "correcthorse"}, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := ioutil.WriteFile(path, bb, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	wrongKey := []byte(strings.Repeat("x", 32))
+	if _, err := loader.OpenEncryptedFile(path, wrongKey); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("Expected a wrong key error. Got %v", err)
+	}
+}
+
+func TestParseSecretsKey(t *testing.T) {
+	if _, err := loader.ParseSecretsKey(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+	key, err := loader.ParseSecretsKey(base64.StdEncoding.EncodeToString(testKey) + "\n")
+	if err != nil || string(key) != string(testKey) {
+		t.Errorf("Expected the key to be decoded. Got %q, %v", key, err)
+	}
+}
+
+func TestVault(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"permission denied"}})
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/v1/kv/data/app/db%3Fv%231":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": map[string]interface{}{"password": "escaped"}},
+			})
+		case "/v1/kv/data/app/db":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"data":     map[string]interface{}{"password": "correcthorse", "user": "app"},
+					"metadata": map[string]interface{}{"version": 3},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{}})
+		}
+	}))
+	defer srv.Close()
+
+	secrets, err := loader.SecretsFromEnv(env(map[string]string{
+		"VAULT_ADDR":  srv.URL,
+		"VAULT_TOKEN": "root",
+		"VAULT_MOUNT": "kv",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c providedConfig
+	_, err = loader.Load(&c,
+		loader.WithEnv(env(map[string]string{
+			"DB_USER":     "secret://app/db/user",
+			"DB_PASSWORD": "secret://app/db/password",
+			"API_KEY":     "secret://app/api/key",
+		})),
+		loader.WithSecrets(secrets),
+	)
+
+	expectedErr := "API_KEY: failed to resolve secret app/api/key. failed to read vault secret app/api. status 404: Not Found"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+	if c.DBUser != "app" || c.DBPassword.Value() != "correcthorse" {
+		t.Errorf("Expected the secrets to be resolved. Got %q, %q", c.DBUser, c.DBPassword.Value())
+	}
+	// both keys of app/db are read with one request.
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected 2 requests. Got %d", n)
+	}
+
+	// the path stays in the path of the request.
+	escaped := &loader.Vault{Addr: srv.URL, Token: "root", Mount: "kv"}
+	if pass, err := escaped.Secret(context.Background(), "app/db?v#1/password"); err != nil || pass != "escaped" {
+		t.Errorf("Expected the secret of the escaped path. Got %q, %v", pass, err)
+	}
+	for _, p := range []string{"../sys/db/password", "app/../../sys/password", "app/./db/password"} {
+		expectedErr := fmt.Sprintf("invalid path %q, elements can't be . or ..", p)
+		if _, err := escaped.Secret(context.Background(), p); err == nil || err.Error() != expectedErr {
+			t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+		}
+	}
+
+	denied := &loader.Vault{Addr: srv.URL, Token: "nope", Mount: "kv"}
+	var c2 providedConfig
+	_, err = loader.Load(&c2, loader.WithEnv(env(map[string]string{"DB_PASSWORD": "secret://app/db/password"})), loader.WithSecrets(denied))
+	expectedErr = "DB_PASSWORD: failed to resolve secret app/db/password. failed to read vault secret app/db. status 403: permission denied"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+}
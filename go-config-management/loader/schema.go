@@ -0,0 +1,341 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the JSON Schema draft the schemas of Schema follow.
+const SchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches the strings time.ParseDuration accepts.
+const durationPattern = `^[-+]?(0|([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`
+
+// Schema returns the JSON Schema of the config files of v, a struct or a map with string keys or
+// a pointer to one, so editors can validate and autocomplete them:
+//   - fields are properties named by their json tag. Keys that don't match any are rejected
+//   - the usage tag is the description and the default tag the default
+//   - required:"true" fields are required. Fields only required in some profiles are not
+//   - the validate rules become the matching keywords, e.g. max=10 becomes maximum or maxLength
+//
+// Config files can point to their schema with a "$schema" key, which Load ignores.
+func Schema(v interface{}) map[string]interface{} {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s := schemaOf(t, "")
+	s["$schema"] = SchemaVersion
+	if props, ok := s["properties"].(map[string]interface{}); ok {
+		props["$schema"] = map[string]interface{}{"type": "string"}
+	} else if t.Kind() == reflect.Map {
+		s["properties"] = map[string]interface{}{"$schema": map[string]interface{}{"type": "string"}}
+	}
+	return s
+}
+
+// schemaOf returns the schema of the type t of a field with the struct tag tag.
+func schemaOf(t reflect.Type, tag reflect.StructTag) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	s := map[string]interface{}{}
+	switch {
+	case t == durationType:
+		s["type"] = "string"
+		s["pattern"] = durationPattern
+	case t == urlType:
+		s["type"] = "string"
+		s["format"] = "uri"
+	case isText(t):
+		s["type"] = "string"
+	case t.Kind() == reflect.Struct:
+		props := map[string]interface{}{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, skip := jsonName(sf)
+			if sf.PkgPath != "" || skip {
+				continue
+			}
+			props[name] = schemaOf(sf.Type, sf.Tag)
+			if sf.Tag.Get("required") == "true" {
+				required = append(required, name)
+			}
+		}
+		s["type"] = "object"
+		s["properties"] = props
+		s["additionalProperties"] = false
+		if len(required) > 0 {
+			s["required"] = required
+		}
+	case t.Kind() == reflect.Map:
+		s["type"] = "object"
+		s["additionalProperties"] = schemaOf(t.Elem(), "")
+	case t.Kind() == reflect.Slice:
+		s["type"] = "array"
+		s["items"] = schemaOf(t.Elem(), "")
+	case t.Kind() == reflect.String:
+		s["type"] = "string"
+	case t.Kind() == reflect.Bool:
+		s["type"] = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		s["type"] = "integer"
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		s["type"] = "integer"
+		s["minimum"] = json.Number("0")
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s["type"] = "number"
+	}
+
+	if usage := tag.Get("usage"); usage != "" {
+		s["description"] = usage
+	}
+	if def, ok := tag.Lookup("default"); ok {
+		s["default"] = schemaValue(s["type"], def)
+	}
+	if rules := tag.Get("validate"); rules != "" && t != durationType {
+		addRules(s, rules)
+	}
+	return s
+}
+
+// addRules adds the keywords matching the validate rules to the schema s, see checkRules.
+func addRules(s map[string]interface{}, rules string) {
+	minKey, maxKey := "minimum", "maximum"
+	switch s["type"] {
+	case "string":
+		minKey, maxKey = "minLength", "maxLength"
+	case "array":
+		minKey, maxKey = "minItems", "maxItems"
+	case "object":
+		minKey, maxKey = "minProperties", "maxProperties"
+	}
+
+	for rules != "" {
+		var rule string
+		if strings.HasPrefix(rules, "regex=") {
+			rule, rules = rules, ""
+		} else if i := strings.Index(rules, ","); i >= 0 {
+			rule, rules = rules[:i], rules[i+1:]
+		} else {
+			rule, rules = rules, ""
+		}
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+
+		switch name {
+		case "nonempty":
+			if minKey != "minimum" {
+				s[minKey] = json.Number("1")
+			}
+		case "min":
+			s[minKey] = json.Number(arg)
+		case "max":
+			s[maxKey] = json.Number(arg)
+		case "port":
+			if s["type"] == "string" {
+				s["pattern"] = "^[0-9]+$"
+			} else {
+				s["minimum"], s["maximum"] = json.Number("1"), json.Number("65535")
+			}
+		case "oneof":
+			var enum []interface{}
+			for _, o := range strings.Fields(arg) {
+				enum = append(enum, schemaValue(s["type"], o))
+			}
+			s["enum"] = enum
+		case "regex":
+			s["pattern"] = arg
+		}
+	}
+}
+
+// schemaValue returns the string v as a value of the schema type typ, e.g. a default or an enum option.
+func schemaValue(typ interface{}, v string) interface{} {
+	switch typ {
+	case "integer", "number":
+		return json.Number(v)
+	case "boolean":
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return v
+}
+
+// validateSchema checks the decoded config file tree against the schema s and returns every violation.
+// It supports the keywords Schema generates.
+func validateSchema(s map[string]interface{}, node interface{}, path string) []string {
+	if typ, ok := s["type"].(string); ok && !hasType(node, typ) {
+		return []string{errorAt(path, fmt.Errorf("expected %s, got %s", article(typ), kind(node))).Error()}
+	}
+
+	var errs []string
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, errorAt(path, fmt.Errorf(format, args...)).Error())
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		props, _ := s["properties"].(map[string]interface{})
+		if required, ok := s["required"].([]string); ok {
+			for _, name := range required {
+				if _, ok := n[name]; !ok {
+					errs = append(errs, join(path, name)+": is required")
+				}
+			}
+		}
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := props[k].(map[string]interface{}); ok {
+				errs = append(errs, validateSchema(ps, n[k], join(path, k))...)
+				continue
+			}
+			switch ap := s["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					known := make([]string, 0, len(props))
+					for name := range props {
+						known = append(known, name)
+					}
+					sort.Strings(known)
+					errs = append(errs, unknownKey(path, k, known))
+				}
+			case map[string]interface{}:
+				errs = append(errs, validateSchema(ap, n[k], join(path, k))...)
+			}
+		}
+		checkSize(s, len(n), "minProperties", "maxProperties", " keys", add)
+	case []interface{}:
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			for i, item := range n {
+				errs = append(errs, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+		checkSize(s, len(n), "minItems", "maxItems", " items", add)
+	case string:
+		checkSize(s, len([]rune(n)), "minLength", "maxLength", " characters", add)
+		if p, ok := s["pattern"].(string); ok {
+			if re, err := regexp.Compile(p); err == nil && !re.MatchString(n) {
+				if p == durationPattern {
+					add("expected a duration string like \"1m30s\", got %q", n)
+				} else {
+					add("must match %s, got %q", p, n)
+				}
+			}
+		}
+	case json.Number:
+		f, _ := n.Float64()
+		if min, ok := s["minimum"].(json.Number); ok {
+			if m, _ := min.Float64(); f < m {
+				add("must be at least %s, got %s", min, n)
+			}
+		}
+		if max, ok := s["maximum"].(json.Number); ok {
+			if m, _ := max.Float64(); f > m {
+				add("must be at most %s, got %s", max, n)
+			}
+		}
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		got := fmt.Sprint(node)
+		options := make([]string, len(enum))
+		found := false
+		for i, o := range enum {
+			options[i] = fmt.Sprint(o)
+			found = found || options[i] == got
+		}
+		if !found {
+			add("must be one of %s, got %q", strings.Join(options, ", "), got)
+		}
+	}
+	return errs
+}
+
+func checkSize(s map[string]interface{}, n int, minKey, maxKey, unit string, add func(string, ...interface{})) {
+	if min, ok := s[minKey].(json.Number); ok {
+		if m, _ := min.Int64(); int64(n) < m {
+			add("must be at least %s%s, got %d", min, unit, n)
+		}
+	}
+	if max, ok := s[maxKey].(json.Number); ok {
+		if m, _ := max.Int64(); int64(n) > m {
+			add("must be at most %s%s, got %d", max, unit, n)
+		}
+	}
+}
+
+// hasType reports whether the decoded node is of the schema type typ. Like Load, it accepts null for
+// any type and numbers and booleans written as strings, e.g. the result of "${RETRIES}".
+func hasType(node interface{}, typ string) bool {
+	switch n := node.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return typ == "object"
+	case []interface{}:
+		return typ == "array"
+	case string:
+		switch typ {
+		case "integer":
+			_, err := strconv.ParseInt(n, 10, 64)
+			return err == nil
+		case "number":
+			_, err := strconv.ParseFloat(n, 64)
+			return err == nil
+		case "boolean":
+			_, err := strconv.ParseBool(n)
+			return err == nil
+		}
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case json.Number:
+		if typ == "integer" {
+			_, err := n.Int64()
+			return err == nil
+		}
+		return typ == "number"
+	}
+	return false
+}
+
+func article(typ string) string {
+	switch typ {
+	case "object", "array", "integer":
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+// kind describes the decoded node in schema errors, which tell integers from other numbers.
+func kind(node interface{}) string {
+	if n, ok := node.(json.Number); ok {
+		if _, err := n.Int64(); err != nil {
+			return "a number"
+		}
+		return "an integer"
+	}
+	return describe(node)
+}
+
+// schemaError lists the schema violations of a config file.
+func schemaError(errs []string) error {
+	if len(errs) == 1 {
+		return fmt.Errorf("%s", errs[0])
+	}
+	return fmt.Errorf("%d schema violations:\n\t%s", len(errs), strings.Join(errs, "\n\t"))
+}
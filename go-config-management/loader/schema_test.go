@@ -0,0 +1,120 @@
+package loader_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"code.com/loader"
+	"github.com/google/go-cmp/cmp"
+)
+
+type schemaJob struct {
+	Schedule string            `json:"schedule" required:"true" usage:"cron expression." validate:"regex=^[^ ]+( [^ ]+){4}$"`
+	Level    string            `json:"level" default:"info" validate:"oneof=debug info"`
+	Retries  int               `json:"retries" validate:"min=0,max=10"`
+	Timeout  time.Duration     `json:"timeout" validate:"min=1s"`
+	Emails   []string          `json:"emails" validate:"max=2"`
+	Args     map[string]string `json:"args"`
+	Internal string            `json:"-"`
+}
+
+func TestSchema(t *testing.T) {
+	bb, err := json.MarshalIndent(loader.Schema(map[string]schemaJob{}), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "additionalProperties": {
+    "additionalProperties": false,
+    "properties": {
+      "args": {
+        "additionalProperties": {
+          "type": "string"
+        },
+        "type": "object"
+      },
+      "emails": {
+        "items": {
+          "type": "string"
+        },
+        "maxItems": 2,
+        "type": "array"
+      },
+      "level": {
+        "default": "info",
+        "enum": [
+          "debug",
+          "info"
+        ],
+        "type": "string"
+      },
+      "retries": {
+        "maximum": 10,
+        "minimum": 0,
+        "type": "integer"
+      },
+      "schedule": {
+        "description": "cron expression.",
+        "pattern": "^[^ ]+( [^ ]+){4}$",
+        "type": "string"
+      },
+      "timeout": {
+        "pattern": "^[-+]?(0|([0-9]*(\\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$",
+        "type": "string"
+      }
+    },
+    "required": [
+      "schedule"
+    ],
+    "type": "object"
+  },
+  "properties": {
+    "$schema": {
+      "type": "string"
+    }
+  },
+  "type": "object"
+}`
+	if diff := cmp.Diff(expected, string(bb)); diff != "" {
+		t.Errorf("schemas are different (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoad_Schema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	content := `{
+  "$schema": "./jobs.schema.json",
+  "inventoryCron": {"schedule": "30 0 * *", "level": "warn", "retry": 3, "timeout": "soon"},
+  "invoicesCron": {"retries": 20, "emails": ["a@code.com", "b@code.com", "c@code.com"]}
+}`
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs map[string]schemaJob
+	_, err := loader.Load(&jobs, loader.WithFile(path), loader.WithSchema(loader.Schema(jobs)))
+
+	expectedErr := "invalid config file " + path + `. 7 schema violations:
+	inventoryCron.level: must be one of debug, info, got "warn"
+	inventoryCron: unknown key "retry", did you mean retries?
+	inventoryCron.schedule: must match ^[^ ]+( [^ ]+){4}$, got "30 0 * *"
+	inventoryCron.timeout: expected a duration string like "1m30s", got "soon"
+	invoicesCron.schedule: is required
+	invoicesCron.emails: must be at most 2 items, got 3
+	invoicesCron.retries: must be at most 10, got 20`
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"$schema": "./jobs.schema.json", "inventoryCron": {"schedule": "30 0 * * *"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.Load(&jobs, loader.WithFile(path), loader.WithSchema(loader.Schema(jobs))); err != nil {
+		t.Errorf("Expected a valid file to load. Got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// Secret is a string that is redacted whenever it is printed or marshaled, e.g. a password.
+// Use Value to get the actual string.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// Value returns the unredacted secret.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String returns "[REDACTED]", or "" if the secret is empty so missing secrets still show up.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString redacts the secret in %#v.
+func (s Secret) GoString() string {
+	return fmt.Sprintf("loader.Secret(%q)", s.String())
+}
+
+// MarshalText redacts the secret in JSON, YAML and any other encoding that uses encoding.TextMarshaler.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// lookupEnv looks up the env var key. If key is unset but key_FILE is set, e.g. DB_PASSWORD_FILE,
+// the value is read from the file it points to, like Docker and Kubernetes secrets.
+// name is the env var the value came from, to be used in errors.
+func (l *loader) lookupEnv(key string) (value, name string, ok bool, err error) {
+	v, ok := l.env(key)
+	path, fileOK := l.env(key + "_FILE")
+	if !fileOK || path == "" {
+		return v, key, ok && v != "", nil
+	}
+	if ok && v != "" {
+		return "", key, false, fmt.Errorf("only one of %s and %s_FILE can be set", key, key)
+	}
+
+	bb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", key + "_FILE", false, fmt.Errorf("failed to read secret file. %v", err)
+	}
+	// editors and `echo` leave a trailing newline which is never part of the secret.
+	return strings.TrimRight(string(bb), "\r\n"), key + "_FILE", true, nil
+}
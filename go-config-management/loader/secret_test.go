@@ -0,0 +1,116 @@
+package loader_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"code.com/loader"
+)
+
+type secretConfig struct {
+	DBHost     string        `env:"DB_HOST" default:"localhost" json:"dbHost"`
+	DBPassword loader.Secret `env:"DB_PASSWORD" flag:"db-password" json:"dbPassword"`
+	Tokens     []loader.Secret
+}
+
+func TestSecret_Redacted(t *testing.T) {
+	c := secretConfig{DBHost: "localhost", DBPassword: "hunter2", Tokens: []loader.Secret{"t1"}}
+
+	bb, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := []string{
+		fmt.Sprint(c),
+		fmt.Sprintf("%v", c),
+		fmt.Sprintf("%+v", c),
+		fmt.Sprintf("%#v", c),
+		fmt.Sprintf("%s", c.DBPassword),
+		string(bb),
+	}
+	for _, out := range outputs {
+		if bytes.Contains([]byte(out), []byte("hunter2")) || bytes.Contains([]byte(out), []byte("t1")) {
+			t.Errorf("Expected the secret to be redacted. Got %s", out)
+		}
+	}
+
+	if c.DBPassword.Value() != "hunter2" {
+		t.Errorf("Expected Value to return the secret. Got %s", c.DBPassword.Value())
+	}
+	if loader.Secret("").String() != "" {
+		t.Errorf("Expected an empty secret to print as empty. Got %s", loader.Secret("").String())
+	}
+}
+
+func TestLoad_SecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_password")
+	if err := ioutil.WriteFile(path, []byte("hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var c secretConfig
+	report, err := loader.Load(&c, loader.WithEnv(env(map[string]string{"DB_PASSWORD_FILE": path})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DBPassword.Value() != "hunter2" {
+		t.Errorf("Expected DBPassword to be read from DB_PASSWORD_FILE. Got %q", c.DBPassword.Value())
+	}
+	if report["DBPassword"] != loader.SourceEnv {
+		t.Errorf("Expected DBPassword to be reported as env. Got %s", report["DBPassword"])
+	}
+
+	_, err = loader.Load(&c, loader.WithEnv(env(map[string]string{"DB_PASSWORD": "x", "DB_PASSWORD_FILE": path})))
+	expectedErr := "DB_PASSWORD: only one of DB_PASSWORD and DB_PASSWORD_FILE can be set"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "nope")
+	_, err = loader.Load(&c, loader.WithEnv(env(map[string]string{"DB_PASSWORD_FILE": missing})))
+	expectedErr = "DB_PASSWORD_FILE: failed to read secret file. open " + missing + ": no such file or directory"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+}
+
+func TestDump(t *testing.T) {
+	var c secretConfig
+	report, err := loader.Load(&c, loader.WithEnv(env(map[string]string{"DB_PASSWORD": "hunter2"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := loader.Dump(&buf, c, report); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `DBHost     = "localhost" (default)
+DBPassword = [REDACTED]  (env)
+Tokens     = []          (unset)
+`
+	if buf.String() != expected {
+		t.Errorf("Expected dump to be\n%s\nGot\n%s", expected, buf.String())
+	}
+}
+
+func TestLoad_SecretFlagUsage(t *testing.T) {
+	type config struct {
+		DBPassword loader.Secret `flag:"db-password" default:"postgres" usage:"database password."`
+	}
+
+	var buf bytes.Buffer
+	fs := quietFlagSet()
+	fs.SetOutput(&buf)
+	var c config
+	loader.Load(&c, loader.WithFlags(fs, []string{"-h"}))
+
+	if bytes.Contains(buf.Bytes(), []byte("postgres")) {
+		t.Errorf("Expected the default of a secret flag to be redacted. Got %s", buf.String())
+	}
+}
@@ -0,0 +1,102 @@
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// unknownKeys returns an error for every key of obj that doesn't match a field of the struct at path.
+func unknownKeys(path string, obj map[string]interface{}, known []string) []string {
+	isKnown := make(map[string]bool, len(known))
+	for _, k := range known {
+		isKnown[k] = true
+	}
+
+	var errs []string
+	for key := range obj {
+		if !isKnown[key] {
+			errs = append(errs, unknownKey(path, key, known))
+		}
+	}
+	sort.Strings(errs)
+	return errs
+}
+
+func unknownKey(path, key string, known []string) string {
+	msg := fmt.Sprintf("unknown key %q", key)
+	if s := closest(key, known); s != "" {
+		msg += fmt.Sprintf(", did you mean %s?", s)
+	}
+	if path != "" {
+		msg = path + ": " + msg
+	}
+	return msg
+}
+
+// unknownKeysError lists the unknown keys of a config file.
+func unknownKeysError(errs []string) error {
+	if len(errs) == 1 {
+		return fmt.Errorf("%s", errs[0])
+	}
+	return fmt.Errorf("%d unknown keys:\n\t%s", len(errs), strings.Join(errs, "\n\t"))
+}
+
+// closest returns the known key most similar to key, or "" if none is similar enough to be a typo of it.
+// Keys that only differ in case, dashes and underscores, e.g. inventory-cron and inventoryCron, always match.
+func closest(key string, known []string) string {
+	best, bestDist := "", -1
+	for _, k := range known {
+		d := distance(normalizeKey(key), normalizeKey(k))
+		if bestDist < 0 || d < bestDist || (d == bestDist && k < best) {
+			best, bestDist = k, d
+		}
+	}
+
+	// allow an edit for every other character, e.g. retry for retries.
+	max := (len(key) + 1) / 2
+	if max < 2 {
+		max = 2
+	}
+	if bestDist < 0 || bestDist > max {
+		return ""
+	}
+	return best
+}
+
+func normalizeKey(s string) string {
+	s = strings.ToLower(s)
+	return strings.NewReplacer("-", "", "_", "").Replace(s)
+}
+
+// distance is the Levenshtein distance of a and b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(nn ...int) int {
+	m := nn[0]
+	for _, n := range nn[1:] {
+		if n < m {
+			m = n
+		}
+	}
+	return m
+}
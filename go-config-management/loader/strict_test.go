@@ -0,0 +1,61 @@
+package loader_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"code.com/loader"
+)
+
+func TestLoad_UnknownKeys(t *testing.T) {
+	testCases := []struct {
+		name, content string
+		expectedErr   string
+	}{
+		{
+			name:        "config.json",
+			content:     `{"db-host": "filehost"}`,
+			expectedErr: `unknown key "db-host", did you mean dbHost?`,
+		},
+		{
+			name:        "config.json",
+			content:     `{"dbHost": "filehost", "cron": {"schedul": "30 0 * * *", "timeout": "1m"}}`,
+			expectedErr: "2 unknown keys:\n\tCron: unknown key \"schedul\", did you mean schedule?\n\tCron: unknown key \"timeout\"",
+		},
+		{
+			name:        "config.json",
+			content:     `{"Ignored": "x"}`,
+			expectedErr: `unknown key "Ignored"`,
+		},
+		{
+			name:        "config.yaml",
+			content:     "dbhost: filehost\n",
+			expectedErr: `unknown key "dbhost", did you mean dbHost?`,
+		},
+		{
+			name:        "config.env",
+			content:     "DB_HOST=filehost\nDB_HOTS=other\n",
+			expectedErr: `line 2: unknown key "DB_HOTS", did you mean DB_HOST?`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name+"/"+tC.expectedErr, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tC.name)
+			if err := ioutil.WriteFile(path, []byte(tC.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var c config
+			_, err := loader.Load(&c, loader.WithFile(path))
+			expectedErr := "invalid config file " + path + ". " + tC.expectedErr
+			if err == nil || err.Error() != expectedErr {
+				t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+			}
+
+			if _, err := loader.Load(&c, loader.WithFile(path), loader.WithStrict(false)); err != nil {
+				t.Errorf("Expected unknown keys to be ignored when not strict. Got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package loader
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	ipType              = reflect.TypeOf(net.IP{})
+)
+
+// isText reports whether values of t are set from a single string even though t is a struct, slice or pointer.
+func isText(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == urlType || t == ipType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// setString sets v from its string representation.
+func setString(v reflect.Value, s string) error {
+	t := v.Type()
+	switch {
+	case t == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case t == urlType:
+		u, err := url.Parse(s)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q", s)
+		}
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	case t == ipType:
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("invalid IP address %q", s)
+		}
+		v.Set(reflect.ValueOf(ip))
+		return nil
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("invalid %s %q. %v", t, s, err)
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return numError("integer", s, t, err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return numError("unsigned integer", s, t, err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return numError("number", s, t, err)
+		}
+		v.SetFloat(n)
+	case reflect.Ptr:
+		p := reflect.New(t.Elem())
+		if err := setString(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+	case reflect.Slice:
+		if strings.TrimSpace(s) == "" {
+			v.Set(reflect.MakeSlice(t, 0, 0))
+			return nil
+		}
+		parts := strings.Split(s, ",")
+		sl := reflect.MakeSlice(t, len(parts), len(parts))
+		for i, p := range parts {
+			if err := setString(sl.Index(i), strings.TrimSpace(p)); err != nil {
+				return fmt.Errorf("item %d: %v", i, err)
+			}
+		}
+		v.Set(sl)
+	case reflect.Map:
+		m := reflect.MakeMap(t)
+		if strings.TrimSpace(s) != "" {
+			for _, entry := range strings.Split(s, ",") {
+				i := strings.Index(entry, "=")
+				if i < 0 {
+					return fmt.Errorf("invalid map entry %q, expected key=value", entry)
+				}
+				key := reflect.New(t.Key()).Elem()
+				if err := setString(key, strings.TrimSpace(entry[:i])); err != nil {
+					return fmt.Errorf("key %q: %v", entry[:i], err)
+				}
+				val := reflect.New(t.Elem()).Elem()
+				if err := setString(val, strings.TrimSpace(entry[i+1:])); err != nil {
+					return fmt.Errorf("key %q: %v", entry[:i], err)
+				}
+				m.SetMapIndex(key, val)
+			}
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+func numError(kind, s string, t reflect.Type, err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("%s %q out of range for %s", kind, s, t)
+	}
+	return fmt.Errorf("invalid %s %q", kind, s)
+}
@@ -0,0 +1,167 @@
+package loader_test
+
+import (
+	"io/ioutil"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"code.com/loader"
+	"github.com/google/go-cmp/cmp"
+)
+
+type level int
+
+func (l *level) UnmarshalText(b []byte) error {
+	switch strings.ToLower(string(b)) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return errUnknownLevel
+	}
+	return nil
+}
+
+var errUnknownLevel = stringError("unknown level")
+
+type stringError string
+
+func (e stringError) Error() string { return string(e) }
+
+type typedConfig struct {
+	Port     int               `env:"PORT"`
+	Workers  uint8             `env:"WORKERS"`
+	Ratio    float64           `env:"RATIO"`
+	Debug    bool              `env:"DEBUG" flag:"debug"`
+	Timeout  time.Duration     `env:"TIMEOUT" default:"30s"`
+	URL      url.URL           `env:"URL"`
+	Proxy    *url.URL          `env:"PROXY"`
+	IP       net.IP            `env:"IP"`
+	Hosts    []string          `env:"HOSTS"`
+	Ports    []int             `env:"PORTS"`
+	Labels   map[string]string `env:"LABELS"`
+	Weights  map[string]int    `env:"WEIGHTS"`
+	Level    level             `env:"LEVEL"`
+	MaxConns *int              `env:"MAX_CONNS"`
+}
+
+func TestLoad_Types(t *testing.T) {
+	var c typedConfig
+	_, err := loader.Load(&c,
+		loader.WithEnv(env(map[string]string{
+			"PORT":      "8080",
+			"WORKERS":   "4",
+			"RATIO":     "0.75",
+			"TIMEOUT":   "1m30s",
+			"URL":       "https://code.com/path?q=1",
+			"PROXY":     "http://proxy:3128",
+			"IP":        "10.0.0.1",
+			"HOSTS":     "a.com, b.com",
+			"PORTS":     "80,443",
+			"LABELS":    "env=prod, team=ops",
+			"WEIGHTS":   "a=1,b=2",
+			"LEVEL":     "info",
+			"MAX_CONNS": "10",
+		})),
+		loader.WithFlags(quietFlagSet(), []string{"-debug"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maxConns := 10
+	expected := typedConfig{
+		Port:     8080,
+		Workers:  4,
+		Ratio:    0.75,
+		Debug:    true,
+		Timeout:  90 * time.Second,
+		URL:      url.URL{Scheme: "https", Host: "code.com", Path: "/path", RawQuery: "q=1"},
+		Proxy:    &url.URL{Scheme: "http", Host: "proxy:3128"},
+		IP:       net.ParseIP("10.0.0.1"),
+		Hosts:    []string{"a.com", "b.com"},
+		Ports:    []int{80, 443},
+		Labels:   map[string]string{"env": "prod", "team": "ops"},
+		Weights:  map[string]int{"a": 1, "b": 2},
+		Level:    1,
+		MaxConns: &maxConns,
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("configs are different (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoad_TypeErrors(t *testing.T) {
+	testCases := []struct {
+		key, value  string
+		expectedErr string
+	}{
+		{key: "PORT", value: "abc", expectedErr: `PORT: invalid integer "abc"`},
+		{key: "WORKERS", value: "300", expectedErr: `WORKERS: unsigned integer "300" out of range for uint8`},
+		{key: "WORKERS", value: "-1", expectedErr: `WORKERS: invalid unsigned integer "-1"`},
+		{key: "RATIO", value: "half", expectedErr: `RATIO: invalid number "half"`},
+		{key: "DEBUG", value: "yes", expectedErr: `DEBUG: invalid boolean "yes"`},
+		{key: "TIMEOUT", value: "30", expectedErr: `TIMEOUT: invalid duration "30"`},
+		{key: "URL", value: "http://[::1", expectedErr: `URL: invalid URL "http://[::1"`},
+		{key: "IP", value: "10.0.0", expectedErr: `IP: invalid IP address "10.0.0"`},
+		{key: "PORTS", value: "80,https", expectedErr: `PORTS: item 1: invalid integer "https"`},
+		{key: "LABELS", value: "env", expectedErr: `LABELS: invalid map entry "env", expected key=value`},
+		{key: "WEIGHTS", value: "a=x", expectedErr: `WEIGHTS: key "a": invalid integer "x"`},
+		{key: "LEVEL", value: "loud", expectedErr: `LEVEL: invalid loader_test.level "loud". unknown level`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.key+"="+tC.value, func(t *testing.T) {
+			var c typedConfig
+			_, err := loader.Load(&c, loader.WithEnv(env(map[string]string{tC.key: tC.value})))
+			if err == nil || err.Error() != tC.expectedErr {
+				t.Errorf("Expected error to be %q. Got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_TypedFlags(t *testing.T) {
+	var c typedConfig
+	_, err := loader.Load(&c, loader.WithFlags(quietFlagSet(), []string{"-debug=nope"}))
+	if err == nil || err.Error() != `-debug: invalid boolean "nope"` {
+		t.Errorf("Expected an invalid boolean error. Got %v", err)
+	}
+}
+
+func TestLoad_TypedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"Port": 8080, "Ratio": 0.5, "Timeout": "5s", "URL": "https://code.com", "IP": "::1", "Ports": [80, 443], "Weights": {"a": 1}}`
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var c typedConfig
+	if _, err := loader.Load(&c, loader.WithFile(path)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := typedConfig{
+		Port:    8080,
+		Ratio:   0.5,
+		Timeout: 5 * time.Second,
+		URL:     url.URL{Scheme: "https", Host: "code.com"},
+		IP:      net.ParseIP("::1"),
+		Ports:   []int{80, 443},
+		Weights: map[string]int{"a": 1},
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("configs are different (-want +got):\n%s", diff)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"Timeout": 5}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.Load(&c, loader.WithFile(path)); err == nil || !strings.HasSuffix(err.Error(), `Timeout: expected a duration string like "1m30s", got a number`) {
+		t.Errorf("Expected a duration error. Got %v", err)
+	}
+}
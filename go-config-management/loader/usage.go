@@ -0,0 +1,101 @@
+package loader
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// usage prints the usage of fs to its output: every flag with its usage, env var and default,
+// followed by the env vars of the fields that have no flag.
+//
+//	Usage of files:
+//	  -db-host string
+//	    	database host. (env DB_HOST) (default "localhost")
+func usage(fs *flag.FlagSet, fields []field) {
+	w := fs.Output()
+	if fs.Name() == "" {
+		fmt.Fprintf(w, "Usage:\n")
+	} else {
+		fmt.Fprintf(w, "Usage of %s:\n", fs.Name())
+	}
+
+	var envOnly []field
+	for _, f := range fields {
+		name := f.tag.Get("flag")
+		if name == "" {
+			if f.tag.Get("env") != "" {
+				envOnly = append(envOnly, f)
+			}
+			continue
+		}
+		printEntry(w, "-"+name, f)
+	}
+
+	if len(envOnly) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Environment variables:\n")
+	for _, f := range envOnly {
+		printEntry(w, f.tag.Get("env"), f)
+	}
+}
+
+// printEntry prints the flag or env var name of f, in the format of flag.PrintDefaults.
+func printEntry(w io.Writer, name string, f field) {
+	fmt.Fprintf(w, "  %s", name)
+	if t := typeName(f.value.Type()); t != "" {
+		fmt.Fprintf(w, " %s", t)
+	}
+	fmt.Fprintf(w, "\n    \t%s%s\n", f.tag.Get("usage"), details(f))
+}
+
+// details returns the env var, default and required profiles of f as shown in the usage.
+func details(f field) string {
+	var b strings.Builder
+	if env := f.tag.Get("env"); env != "" && f.tag.Get("flag") != "" {
+		fmt.Fprintf(&b, " (env %s)", env)
+	}
+	if def := f.tag.Get("default"); def != "" {
+		if f.value.Type() == secretType {
+			def = Secret(def).String()
+		}
+		fmt.Fprintf(&b, " (default %q)", def)
+	}
+	switch req := f.tag.Get("required"); req {
+	case "":
+	case "true":
+		b.WriteString(" (required)")
+	default:
+		fmt.Fprintf(&b, " (required in %s)", strings.ReplaceAll(req, ",", ", "))
+	}
+	return b.String()
+}
+
+// typeName returns the name of the value type of t shown in the usage. Bool flags have none.
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return "duration"
+	case t.Kind() == reflect.Bool:
+		return ""
+	case isText(t):
+		return "value"
+	case t.Kind() == reflect.Slice, t.Kind() == reflect.Map:
+		return "list"
+	case t.Kind() == reflect.String:
+		return "string"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		return "int"
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		return "uint"
+	case t.Kind() == reflect.Float32, t.Kind() == reflect.Float64:
+		return "float"
+	}
+	return "value"
+}
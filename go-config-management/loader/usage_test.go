@@ -0,0 +1,48 @@
+package loader_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"testing"
+	"time"
+
+	"code.com/loader"
+)
+
+type usageConfig struct {
+	DBHost     string        `env:"DB_HOST" flag:"db-host" default:"localhost" usage:"database host."`
+	DBPassword loader.Secret `env:"DB_PASSWORD" flag:"db-password" default:"postgres" required:"staging,prod" usage:"database password."`
+	Timeout    time.Duration `flag:"timeout" default:"5s" usage:"request timeout."`
+	Debug      bool          `flag:"debug" usage:"log debug messages."`
+	Token      string        `env:"TOKEN" required:"true" usage:"api token."`
+}
+
+func TestLoad_Help(t *testing.T) {
+	var out bytes.Buffer
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&out)
+
+	var c usageConfig
+	_, err := loader.Load(&c, loader.WithFlags(fs, []string{"--help"}))
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("Expected flag.ErrHelp. Got %v", err)
+	}
+
+	expected := `Usage of app:
+  -db-host string
+    	database host. (env DB_HOST) (default "localhost")
+  -db-password string
+    	database password. (env DB_PASSWORD) (default "[REDACTED]") (required in staging, prod)
+  -timeout duration
+    	request timeout. (default "5s")
+  -debug
+    	log debug messages.
+Environment variables:
+  TOKEN string
+    	api token. (required)
+`
+	if out.String() != expected {
+		t.Errorf("Expected usage to be\n%s\nGot\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,311 @@
+package loader
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError is a missing or invalid field.
+type FieldError struct {
+	Field string // Path of the field, e.g. "CronConfigs.InventoryCron.Schedule"
+	Key   string // Env var, flag or path the field is known by to users
+	Msg   string
+}
+
+func (e FieldError) Error() string {
+	return e.Key + ": " + e.Msg
+}
+
+// Errors lists every missing or invalid field found by Load.
+type Errors []FieldError
+
+func (ee Errors) Error() string {
+	if len(ee) == 1 {
+		return ee[0].Error()
+	}
+	ss := make([]string, len(ee))
+	for i, e := range ee {
+		ss[i] = e.Error()
+	}
+	return fmt.Sprintf("%d invalid config fields:\n\t%s", len(ee), strings.Join(ss, "\n\t"))
+}
+
+// key returns the name users know the field by: its env var, its flag or its path in config files.
+func key(keyPath string, tag reflect.StructTag) string {
+	if env := tag.Get("env"); env != "" {
+		return env
+	}
+	if fl := tag.Get("flag"); fl != "" {
+		return "-" + fl
+	}
+	return keyPath
+}
+
+// validate checks the required and validate tags of the struct v and its nested structs,
+// including the ones in slices and maps. Fields that already failed to load are skipped.
+// keyPath is the path of v in config files, e.g. "inventoryCron" for "CronConfigs.InventoryCron".
+// Required fields are only checked if checkRequired is true, which is not the case for the fields of
+// an optional nested struct that wasn't set at all.
+func (l *loader) validate(v reflect.Value, path, keyPath string, inContainer, checkRequired bool) Errors {
+	var errs Errors
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		fv := v.Field(i)
+		fpath := join(path, sf.Name)
+		if l.failed[fpath] {
+			continue
+		}
+		name, _ := jsonName(sf)
+		fkey := key(join(keyPath, name), sf.Tag)
+
+		req, ok := sf.Tag.Lookup("required")
+		required := ok && l.requiredIn(req)
+		if checkRequired && required && !l.present(fv, fpath, inContainer) {
+			msg := "is required"
+			if req != "true" {
+				msg += " in " + l.profile
+			}
+			errs = append(errs, FieldError{Field: fpath, Key: fkey, Msg: msg})
+			continue
+		}
+
+		if rules := sf.Tag.Get("validate"); rules != "" {
+			if msg := checkRules(fv, rules); msg != "" {
+				errs = append(errs, FieldError{Field: fpath, Key: fkey, Msg: msg})
+				continue
+			}
+		}
+
+		nestedRequired := checkRequired && (required || l.present(fv, fpath, inContainer))
+		errs = append(errs, l.validateNested(fv, fpath, join(keyPath, name), inContainer, nestedRequired)...)
+	}
+	return errs
+}
+
+func (l *loader) validateNested(v reflect.Value, path, keyPath string, inContainer, checkRequired bool) Errors {
+	switch {
+	case v.Kind() == reflect.Struct && !isText(v.Type()):
+		return l.validate(v, path, keyPath, inContainer, checkRequired)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		var errs Errors
+		for i := 0; i < v.Len(); i++ {
+			errs = append(errs, l.validate(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("%s[%d]", keyPath, i), true, true)...)
+		}
+		return errs
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.Struct:
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = k.String()
+		}
+		sort.Strings(names)
+		var errs Errors
+		for _, name := range names {
+			// map values are not addressable, validate a copy.
+			ev := reflect.New(v.Type().Elem()).Elem()
+			ev.Set(v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())))
+			errs = append(errs, l.validate(ev, index(path, name), join(keyPath, name), true, true)...)
+		}
+		return errs
+	}
+	return nil
+}
+
+// requiredIn reports whether a field with the required tag req is required in the current profile.
+// req is either "true" or a comma separated list of profiles, e.g. "staging,prod".
+func (l *loader) requiredIn(req string) bool {
+	if req == "true" {
+		return true
+	}
+	for _, p := range strings.Split(req, ",") {
+		if strings.TrimSpace(p) == l.profile && l.profile != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// present reports whether the field was set by a file, env var or flag. Defaults don't count.
+// Fields in slices and maps aren't in the report, so they only need to be non-zero.
+func (l *loader) present(v reflect.Value, path string, inContainer bool) bool {
+	if inContainer {
+		return !v.IsZero()
+	}
+	for p, src := range l.report {
+		if src == SourceDefault {
+			continue
+		}
+		if p == path || strings.HasPrefix(p, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRules checks v against the comma separated rules and returns why it fails, or "" if it doesn't.
+// Rules other than nonempty are skipped for zero values, mark the field required to reject them.
+//
+//	nonempty      the value is not the zero value
+//	min=n, max=n  numbers are compared by value, durations by their duration string, strings, slices and maps by length
+//	port          an integer between 1 and 65535
+//	oneof=a b c   the value is one of the space separated options
+//	regex=re      strings match re. Must be the last rule since re may contain commas
+func checkRules(v reflect.Value, rules string) string {
+	for rules != "" {
+		var rule string
+		if strings.HasPrefix(rules, "regex=") {
+			rule, rules = rules, ""
+		} else if i := strings.Index(rules, ","); i >= 0 {
+			rule, rules = rules[:i], rules[i+1:]
+		} else {
+			rule, rules = rules, ""
+		}
+
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+
+		if name == "nonempty" {
+			if v.IsZero() {
+				return "must not be empty"
+			}
+			continue
+		}
+		if v.IsZero() {
+			continue
+		}
+
+		var msg string
+		switch name {
+		case "min", "max":
+			msg = checkBound(v, name, arg)
+		case "port":
+			msg = checkPort(v)
+		case "oneof":
+			msg = checkOneOf(v, arg)
+		case "regex":
+			msg = checkRegex(v, arg)
+		default:
+			msg = fmt.Sprintf("unknown validation rule %q", name)
+		}
+		if msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func checkBound(v reflect.Value, name, arg string) string {
+	var (
+		got, limit float64
+		unit       string
+		err        error
+	)
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(arg)
+		got, limit = float64(v.Int()), float64(d)
+		if err == nil && !inBound(got, limit, name) {
+			return fmt.Sprintf("must be at %s %s, got %s", minMax(name), d, time.Duration(v.Int()))
+		}
+		return ruleError(err, name, arg)
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		got = float64(v.Int())
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		got = float64(v.Uint())
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		got = v.Float()
+	case v.Kind() == reflect.String:
+		got, unit = float64(len([]rune(v.String()))), " characters"
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Map:
+		got, unit = float64(v.Len()), " items"
+	default:
+		return fmt.Sprintf("%s rule is not supported for %s", name, v.Type())
+	}
+
+	if limit, err = strconv.ParseFloat(arg, 64); err != nil {
+		return ruleError(err, name, arg)
+	}
+	if !inBound(got, limit, name) {
+		return fmt.Sprintf("must be at %s %s%s, got %s", minMax(name), arg, unit, strconv.FormatFloat(got, 'f', -1, 64))
+	}
+	return ""
+}
+
+func inBound(got, limit float64, name string) bool {
+	if name == "min" {
+		return got >= limit
+	}
+	return got <= limit
+}
+
+func minMax(name string) string {
+	if name == "min" {
+		return "least"
+	}
+	return "most"
+}
+
+func ruleError(err error, name, arg string) string {
+	if err != nil {
+		return fmt.Sprintf("invalid %s rule %q", name, arg)
+	}
+	return ""
+}
+
+func checkPort(v reflect.Value) string {
+	var n int64
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		n = v.Int()
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		n = int64(v.Uint())
+	case v.Kind() == reflect.String:
+		var err error
+		if n, err = strconv.ParseInt(v.String(), 10, 64); err != nil {
+			return fmt.Sprintf("must be a port number, got %q", v.String())
+		}
+	default:
+		return fmt.Sprintf("port rule is not supported for %s", v.Type())
+	}
+	if n < 1 || n > 65535 {
+		return fmt.Sprintf("must be a port between 1 and 65535, got %d", n)
+	}
+	return ""
+}
+
+func checkOneOf(v reflect.Value, arg string) string {
+	got := fmt.Sprint(v.Interface())
+	options := strings.Fields(arg)
+	for _, o := range options {
+		if got == o {
+			return ""
+		}
+	}
+	return fmt.Sprintf("must be one of %s, got %q", strings.Join(options, ", "), got)
+}
+
+func checkRegex(v reflect.Value, arg string) string {
+	if v.Kind() != reflect.String {
+		return fmt.Sprintf("regex rule is not supported for %s", v.Type())
+	}
+	re, err := regexp.Compile(arg)
+	if err != nil {
+		return fmt.Sprintf("invalid regex rule %q", arg)
+	}
+	if !re.MatchString(v.String()) {
+		return fmt.Sprintf("must match %s, got %q", arg, v.String())
+	}
+	return ""
+}
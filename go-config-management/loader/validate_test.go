@@ -0,0 +1,125 @@
+package loader_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"code.com/loader"
+	"github.com/google/go-cmp/cmp"
+)
+
+type job struct {
+	Schedule string `json:"schedule" required:"true"`
+	Retries  int    `json:"retries" validate:"max=5"`
+}
+
+type validatedConfig struct {
+	Env        string        `env:"APP_ENV" default:"local" validate:"oneof=local staging prod"`
+	DBHost     string        `env:"DB_HOST" default:"localhost" validate:"nonempty"`
+	DBPort     int           `env:"DB_PORT" default:"5432" validate:"port"`
+	DBUser     string        `env:"DB_USER" default:"postgres" required:"prod"`
+	DBPassword string        `env:"DB_PASSWORD" default:"postgres" required:"staging,prod" validate:"min=8"`
+	DBName     string        `env:"DB_NAME" validate:"regex=^[a-z_]{1,63}$"`
+	Timeout    time.Duration `env:"TIMEOUT" default:"5s" validate:"min=1s,max=1m"`
+	Token      string        `env:"TOKEN" required:"true"`
+	Job        job           `json:"job" required:"true"`
+	Jobs       []job         `json:"jobs"`
+	Backup     job           `json:"backup"` // optional, its schedule is only required if it is set
+}
+
+func TestLoad_Validate(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		profile     string
+		vars        map[string]string
+		file        string
+		expectedErr []loader.FieldError
+	}{
+		{
+			desc:    "valid",
+			profile: "prod",
+			vars: map[string]string{
+				"TOKEN":       "secret",
+				"DB_USER":     "app",
+				"DB_PASSWORD": "correcthorsebattery",
+				"DB_NAME":     "orders",
+			},
+			file: "testdata/validate.json",
+		},
+		{
+			desc:    "defaults are enough locally",
+			profile: "local",
+			vars:    map[string]string{"TOKEN": "secret"},
+			file:    "testdata/validate.json",
+		},
+		{
+			desc:    "every invalid field is reported",
+			profile: "prod",
+			vars: map[string]string{
+				"APP_ENV":     "production",
+				"DB_PORT":     "70000",
+				"DB_PASSWORD": "short",
+				"DB_NAME":     "Orders",
+				"TIMEOUT":     "2m",
+			},
+			file: "testdata/validate_invalid.json",
+			expectedErr: []loader.FieldError{
+				{Field: "Env", Key: "APP_ENV", Msg: `must be one of local, staging, prod, got "production"`},
+				{Field: "DBPort", Key: "DB_PORT", Msg: "must be a port between 1 and 65535, got 70000"},
+				{Field: "DBUser", Key: "DB_USER", Msg: "is required in prod"},
+				{Field: "DBPassword", Key: "DB_PASSWORD", Msg: "must be at least 8 characters, got 5"},
+				{Field: "DBName", Key: "DB_NAME", Msg: `must match ^[a-z_]{1,63}$, got "Orders"`},
+				{Field: "Timeout", Key: "TIMEOUT", Msg: "must be at most 1m0s, got 2m0s"},
+				{Field: "Token", Key: "TOKEN", Msg: "is required"},
+				{Field: "Job", Key: "job", Msg: "is required"},
+				{Field: "Jobs[0].Schedule", Key: "jobs[0].schedule", Msg: "is required"},
+				{Field: "Jobs[1].Retries", Key: "jobs[1].retries", Msg: "must be at most 5, got 10"},
+				{Field: "Backup.Schedule", Key: "backup.schedule", Msg: "is required"},
+			},
+		},
+		{
+			desc:    "invalid values are reported with failed rules",
+			profile: "local",
+			vars:    map[string]string{"DB_PORT": "abc", "DB_HOST": "localhost"},
+			file:    "testdata/validate.json",
+			expectedErr: []loader.FieldError{
+				{Field: "DBPort", Key: "DB_PORT", Msg: `invalid integer "abc"`},
+				{Field: "Token", Key: "TOKEN", Msg: "is required"},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var c validatedConfig
+			_, err := loader.Load(&c,
+				loader.WithFile(tC.file),
+				loader.WithEnv(env(tC.vars)),
+				loader.WithProfile(tC.profile),
+			)
+
+			var errs loader.Errors
+			if err != nil && !errors.As(err, &errs) {
+				t.Fatalf("Expected a loader.Errors. Got %v", err)
+			}
+			if diff := cmp.Diff(loader.Errors(tC.expectedErr), errs); diff != "" {
+				t.Errorf("errors are different (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestErrors_Error(t *testing.T) {
+	errs := loader.Errors{
+		{Field: "DBPort", Key: "DB_PORT", Msg: "must be a port between 1 and 65535, got 70000"},
+		{Field: "Token", Key: "TOKEN", Msg: "is required"},
+	}
+	expected := "2 invalid config fields:\n\tDB_PORT: must be a port between 1 and 65535, got 70000\n\tTOKEN: is required"
+	if errs.Error() != expected {
+		t.Errorf("Expected error to be %q. Got %q", expected, errs.Error())
+	}
+
+	if errs[:1].Error() != "DB_PORT: must be a port between 1 and 65535, got 70000" {
+		t.Errorf("Expected a single error to not be numbered. Got %q", errs[:1].Error())
+	}
+}
@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vault is a SecretProvider reading secrets from a HashiCorp Vault KV version 2 secrets engine, or
+// any server implementing its HTTP API. The last element of a secret path is the key in the secret
+// the rest points to, e.g. db/password is the password key of the secret db.
+type Vault struct {
+	Addr   string       // e.g. https://vault.internal:8200
+	Token  string       // Sent as X-Vault-Token
+	Mount  string       // Path the engine is mounted at. Defaults to secret
+	Client *http.Client // Defaults to a client with a 10 second timeout
+
+	mu    sync.Mutex
+	cache map[string]map[string]interface{} // secrets by path, so keys of the same secret are read once
+}
+
+// Secret returns the secret at path. Elements of the path can't be . or .., which would point
+// outside the engine once the request path is cleaned.
+func (v *Vault) Secret(ctx context.Context, p string) (string, error) {
+	dir, key := path.Split(p)
+	dir = strings.Trim(dir, "/")
+	if dir == "" || key == "" {
+		return "", fmt.Errorf("expected a path like db/password, got %q", p)
+	}
+	for _, elem := range strings.Split(p, "/") {
+		if elem == "." || elem == ".." {
+			return "", fmt.Errorf("invalid path %q, elements can't be . or ..", p)
+		}
+	}
+
+	data, err := v.read(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in vault secret %s", key, dir)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %s of vault secret %s is not a string", key, dir)
+	}
+	return s, nil
+}
+
+// read returns the data of the latest version of the secret at p.
+func (v *Vault) read(ctx context.Context, p string) (map[string]interface{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if data, ok := v.cache[p]; ok {
+		return data, nil
+	}
+
+	mount := v.Mount
+	if mount == "" {
+		mount = "secret"
+	}
+	u, err := url.Parse(strings.TrimSuffix(v.Addr, "/") + "/v1/" + escapePath(mount) + "/data/" + escapePath(p))
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault address. %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault request. %v", err)
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s. %v", p, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to decode vault response. %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := strings.Join(body.Errors, ", ")
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to read vault secret %s. status %d: %s", p, resp.StatusCode, msg)
+	}
+
+	if v.cache == nil {
+		v.cache = map[string]map[string]interface{}{}
+	}
+	v.cache[p] = body.Data.Data
+	return body.Data.Data, nil
+}
+
+// escapePath escapes every element of p, so characters like ? and # stay in the path of the request.
+func escapePath(p string) string {
+	elems := strings.Split(p, "/")
+	for i, elem := range elems {
+		elems[i] = url.PathEscape(elem)
+	}
+	return strings.Join(elems, "/")
+}
@@ -0,0 +1,91 @@
+// Package api serves the products over HTTP.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code.com/paginate"
+	"code.com/paginate/cursor"
+	"code.com/postgres"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type productResponse struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Name      string    `json:"name"`
+}
+
+type productsResponse struct {
+	Products []productResponse `json:"products"`
+	Cursors  struct {
+		Prev string `json:"prev,omitempty"`
+		Next string `json:"next,omitempty"`
+	} `json:"cursors"`
+}
+
+// Products serves a page of products, newest first: GET /products?limit=20&next=<cursor>, or
+// prev=<cursor> for the page before. The cursors of the pages around it are in the response.
+// sort picks another order, e.g. sort=name or sort=-id for descending ids, see postgres.ParseSort.
+// Cursors must be sent with the sort of the page they came from.
+// Invalid limits, sorts and cursors are answered with 400 Bad Request.
+func Products(store postgres.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		limit := defaultLimit
+		if s := q.Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > maxLimit {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxLimit), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if q.Get("next") != "" && q.Get("prev") != "" {
+			http.Error(w, "only one of next and prev can be given", http.StatusBadRequest)
+			return
+		}
+
+		sort := postgres.DefaultSort
+		if s := q.Get("sort"); s != "" {
+			var err error
+			if sort, err = postgres.ParseSort(s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		page, err := store.GetProducts(r.Context(), sort, paginate.Cursors{Prev: q.Get("prev"), Next: q.Get("next")}, limit)
+		var invalid *cursor.InvalidError
+		if errors.As(err, &invalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to get products. %v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		resp := productsResponse{Products: make([]productResponse, len(page.Items))}
+		for i, p := range page.Items {
+			resp.Products[i] = productResponse{ID: int(p.ID), CreatedAt: p.CreatedAt, Name: p.Name}
+		}
+		resp.Cursors.Prev, resp.Cursors.Next = page.Cursors.Prev, page.Cursors.Next
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.com/api"
+	"code.com/paginate/cursor"
+	"code.com/postgres"
+	"code.com/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+var codec = cursor.Codec{Key: []byte("0123456789abcdef0123456789abcdef"), TTL: time.Hour}
+
+func TestProducts_BadRequest(t *testing.T) {
+	// every request is rejected before the database is queried.
+	h := api.Products(postgres.NewStore(nil, codec))
+	forged := cursor.Codec{Key: []byte("forged-key")}.Encode(cursor.Cursor{Direction: cursor.Next})
+	newest := codec.Encode(cursor.Cursor{Direction: cursor.Next, Sort: "created_at DESC, id DESC", Values: []string{"2022-05-26T13:29:16Z", "4"}})
+
+	testCases := []struct {
+		desc         string
+		query        string
+		expectedBody string
+	}{
+		{desc: "limit not a number", query: "limit=ten", expectedBody: "limit must be between 1 and 100"},
+		{desc: "limit too big", query: "limit=1000", expectedBody: "limit must be between 1 and 100"},
+		{desc: "both cursors", query: "next=a&prev=b", expectedBody: "only one of next and prev can be given"},
+		{desc: "raw timestamp cursor", query: "next=2022-05-26T13:29:16Z", expectedBody: "invalid cursor. malformed token"},
+		{desc: "forged cursor", query: "next=" + forged, expectedBody: "invalid cursor. bad signature"},
+		{desc: "sort not allowed", query: "sort=price", expectedBody: `can't sort by "price", only by name, created_at or id`},
+		{
+			desc:         "cursor of another sort",
+			query:        "sort=name&next=" + newest,
+			expectedBody: `invalid cursor. cursor of sort "created_at DESC, id DESC" used for "name ASC, id ASC"`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/products?"+tC.query, nil))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status to be %d. Got %d", http.StatusBadRequest, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tC.expectedBody {
+				t.Errorf("Expected body to be %q. Got %q", tC.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestProducts(t *testing.T) {
+	db := test.SetupDB(t)
+	test.CreateProductTable(t, db)
+
+	_, err := db.Exec(`
+		INSERT INTO products(created_at, name)
+		VALUES
+			('2022-05-23 13:29:16', 'Shirt'),
+			('2022-05-24 13:29:16', 'Polo'),
+			('2022-05-25 13:29:16', 'T-Shirt')
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := api.Products(postgres.NewStore(db, codec))
+
+	get := func(query string) (names []string, prev, next string) {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/products?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status to be %d. Got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var resp struct {
+			Products []struct {
+				Name string `json:"name"`
+			} `json:"products"`
+			Cursors struct {
+				Prev string `json:"prev"`
+				Next string `json:"next"`
+			} `json:"cursors"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range resp.Products {
+			names = append(names, p.Name)
+		}
+		return names, resp.Cursors.Prev, resp.Cursors.Next
+	}
+
+	names, _, next := get("limit=2")
+	if diff := cmp.Diff([]string{"T-Shirt", "Polo"}, names); diff != "" {
+		t.Errorf("products are different (-want +got):\n%s", diff)
+	}
+
+	names, prev, _ := get("limit=2&next=" + next)
+	if diff := cmp.Diff([]string{"Shirt"}, names); diff != "" {
+		t.Errorf("products are different (-want +got):\n%s", diff)
+	}
+
+	names, _, _ = get("limit=2&prev=" + prev)
+	if diff := cmp.Diff([]string{"T-Shirt", "Polo"}, names); diff != "" {
+		t.Errorf("products are different (-want +got):\n%s", diff)
+	}
+
+	names, _, next = get("limit=2&sort=name")
+	if diff := cmp.Diff([]string{"Polo", "Shirt"}, names); diff != "" {
+		t.Errorf("products are different (-want +got):\n%s", diff)
+	}
+
+	names, _, _ = get("limit=2&sort=name&next=" + next)
+	if diff := cmp.Diff([]string{"T-Shirt"}, names); diff != "" {
+		t.Errorf("products are different (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,116 @@
+package cursor_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"code.com/paginate/cursor"
+	"github.com/google/go-cmp/cmp"
+)
+
+var key = []byte("0123456789abcdef0123456789abcdef")
+
+func TestCodec(t *testing.T) {
+	now := time.Date(2022, 5, 26, 13, 29, 16, 0, time.UTC)
+	codec := cursor.Codec{Key: key, TTL: time.Hour, Now: func() time.Time { return now }}
+
+	token := codec.Encode(cursor.Cursor{
+		Direction: cursor.Next,
+		Sort:      "created_at DESC, id DESC",
+		Values:    []string{"2022-05-26T13:29:16Z", "4"},
+	})
+	if _, err := base64.RawURLEncoding.DecodeString(token); err != nil {
+		t.Errorf("Expected a base64url token. Got %s", token)
+	}
+
+	c, err := codec.Decode(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cursor.Cursor{
+		Version:   cursor.Version,
+		Direction: cursor.Next,
+		Sort:      "created_at DESC, id DESC",
+		Values:    []string{"2022-05-26T13:29:16Z", "4"},
+		Expires:   now.Add(time.Hour).Unix(),
+	}
+	if diff := cmp.Diff(expected, c); diff != "" {
+		t.Errorf("cursors are different (-want +got):\n%s", diff)
+	}
+
+	// the cursor expires after the TTL.
+	now = now.Add(time.Hour)
+	_, err = codec.Decode(token)
+	var invalid *cursor.InvalidError
+	if !errors.As(err, &invalid) || invalid.Reason != "expired" {
+		t.Errorf("Expected the cursor to be expired. Got %v", err)
+	}
+}
+
+func TestCodec_Invalid(t *testing.T) {
+	codec := cursor.Codec{Key: key}
+	token := codec.Encode(cursor.Cursor{Direction: cursor.Prev, Values: []string{"a"}})
+	bb, _ := base64.RawURLEncoding.DecodeString(token)
+	sign := func(payload string) string {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(payload))
+		return base64.RawURLEncoding.EncodeToString(append(h.Sum(nil), payload...))
+	}
+
+	testCases := []struct {
+		desc        string
+		token       string
+		expectedErr string
+	}{
+		{desc: "empty", token: "", expectedErr: "invalid cursor. malformed token"},
+		{desc: "not base64url", token: token + "=", expectedErr: "invalid cursor. malformed token"},
+		{desc: "shorter than a signature", token: base64.RawURLEncoding.EncodeToString(bb[:10]), expectedErr: "invalid cursor. malformed token"},
+		{desc: "payload changed", token: base64.RawURLEncoding.EncodeToString(append(bb[:len(bb)-2:len(bb)-2], '}', '}')), expectedErr: "invalid cursor. bad signature"},
+		{desc: "signed with another key", token: cursor.Codec{Key: []byte("another key")}.Encode(cursor.Cursor{}), expectedErr: "invalid cursor. bad signature"},
+		{desc: "unsigned", token: base64.RawURLEncoding.EncodeToString(bb[32:]), expectedErr: "invalid cursor. bad signature"},
+		{desc: "signed garbage", token: sign("not json"), expectedErr: "invalid cursor. malformed token"},
+		{desc: "another version", token: sign(`{"v":2,"d":"next"}`), expectedErr: "invalid cursor. unsupported version 2"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := codec.Decode(tC.token)
+			if err == nil || err.Error() != tC.expectedErr {
+				t.Errorf("Expected error to be %q. Got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestNewCodec(t *testing.T) {
+	if _, err := cursor.NewCodec(nil, time.Hour); !errors.Is(err, cursor.ErrNoKey) {
+		t.Errorf("Expected %v. Got %v", cursor.ErrNoKey, err)
+	}
+
+	codec, err := cursor.NewCodec(key, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := codec.Decode(codec.Encode(cursor.Cursor{Direction: cursor.Next})); err != nil {
+		t.Errorf("Expected the token to decode. Got %v", err)
+	}
+}
+
+func TestCodec_NoKey(t *testing.T) {
+	// anyone can sign a token with an empty key.
+	forged := cursor.Codec{}.Encode(cursor.Cursor{Direction: cursor.Next, Values: []string{"1"}})
+
+	for _, codec := range []cursor.Codec{{}, {Key: []byte{}}} {
+		_, err := codec.Decode(forged)
+		if !errors.Is(err, cursor.ErrNoKey) {
+			t.Errorf("Expected %v. Got %v", cursor.ErrNoKey, err)
+		}
+		var invalid *cursor.InvalidError
+		if errors.As(err, &invalid) {
+			t.Errorf("Expected a missing key to not be blamed on the cursor. Got %v", err)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+module code.com/paginate
+
+go 1.18
+
+require github.com/google/go-cmp v0.5.8
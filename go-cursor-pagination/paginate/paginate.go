@@ -0,0 +1,262 @@
+// Package paginate pages through the results of SQL queries with keyset pagination: a page starts
+// right after the sort key values of the last row of the page before it, so rows inserted or deleted
+// meanwhile don't shift the pages like they do with OFFSET.
+package paginate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"code.com/paginate/cursor"
+)
+
+// Querier runs queries, e.g. a *sql.DB or a *sql.Tx.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Column is a sort column of a query.
+type Column struct {
+	Name string // Column of the query, e.g. created_at. It's part of the SQL, so it must not come from users
+	Desc bool
+}
+
+func (c Column) String() string {
+	if c.Desc {
+		return c.Name + " DESC"
+	}
+	return c.Name + " ASC"
+}
+
+// Query is a query to page through.
+type Query[T any] struct {
+	SQL  string        // e.g. SELECT id, created_at, name FROM products. It can have a WHERE but no ORDER BY or LIMIT
+	Args []interface{} // Arguments of SQL, which can use $1 to $len(Args)
+	// Sort columns of SQL. The columns must be NOT NULL and together unique, so the last one is usually the id.
+	Sort []Column
+	// Scan scans a row of SQL into an item by passing scan the destinations of its columns.
+	Scan func(scan func(dest ...interface{}) error) (T, error)
+	// Key returns the values of the Sort columns of an item, as text Postgres can cast to their types,
+	// e.g. timestamps formatted as RFC 3339.
+	Key func(item T) []string
+}
+
+// Cursors are the opaque tokens of the pages before and after a page, see cursor.Codec.
+type Cursors struct {
+	Prev string
+	Next string
+}
+
+// Page is a page of items and the cursors of the pages around it. A cursor is empty if there is no page there.
+type Page[T any] struct {
+	Items   []T
+	Cursors Cursors
+}
+
+// Fetch returns the page of limit items of q after cursors.Next, or before cursors.Prev, or the first page
+// if neither is given. Items are sorted by q.Sort. Cursors that are malformed, tampered with, expired
+// or of another sort are rejected with a *cursor.InvalidError.
+func Fetch[T any](ctx context.Context, db Querier, codec cursor.Codec, q Query[T], cursors Cursors, limit int) (Page[T], error) {
+	empty := Page[T]{Items: []T{}}
+	if limit == 0 {
+		return empty, errors.New("limit cannot be zero")
+	}
+	if cursors.Next != "" && cursors.Prev != "" {
+		return empty, errors.New("two cursors cannot be provided at the same time")
+	}
+	if len(q.Sort) == 0 {
+		return empty, errors.New("query has no sort columns")
+	}
+
+	spec := sortSpec(q.Sort)
+	order := spec
+	values := append([]interface{}{}, q.Args...)
+	where := ""
+
+	// Going forward
+	if cursors.Next != "" {
+		keys, err := decode(codec, cursors.Next, cursor.Next, spec, len(q.Sort))
+		if err != nil {
+			return empty, err
+		}
+		where = "WHERE " + after(q.Sort, len(values)+1)
+		values = append(values, keys...)
+	}
+
+	// Going backward: the rows after the cursor in the reverse order, which are sorted back below.
+	if cursors.Prev != "" {
+		keys, err := decode(codec, cursors.Prev, cursor.Prev, spec, len(q.Sort))
+		if err != nil {
+			return empty, err
+		}
+		reversed := reverse(q.Sort)
+		where = "WHERE " + after(reversed, len(values)+1)
+		order = sortSpec(reversed)
+		values = append(values, keys...)
+	}
+	values = append(values, limit)
+
+	stmt := fmt.Sprintf(`
+		WITH b AS (
+			%s
+		), p AS (
+			SELECT * FROM b %s ORDER BY %s LIMIT $%d
+		)
+		SELECT p.*,
+		(SELECT COUNT(*) FROM b %s) AS rows_left,
+		(SELECT COUNT(*) FROM b) AS total
+		FROM p
+		ORDER BY %s
+	`, q.SQL, where, order, len(values), where, sortSpec(q.Sort))
+
+	rows, err := db.QueryContext(ctx, stmt, values...)
+	if err != nil {
+		return empty, fmt.Errorf("failed to get page. error: %v", err)
+	}
+	defer rows.Close()
+
+	var (
+		rowsLeft int
+		total    int
+		items    = []T{}
+	)
+
+	for rows.Next() {
+		item, err := q.Scan(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &rowsLeft, &total)...)
+		})
+		if err != nil {
+			return empty, err
+		}
+
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return empty, fmt.Errorf("failed to get page. error: %v", err)
+	}
+
+	var (
+		prevCursor string // cursor we return when there is a previous page
+		nextCursor string // cursor we return when there is a next page
+	)
+	encode := func(dir cursor.Direction, item T) string {
+		return codec.Encode(cursor.Cursor{Direction: dir, Sort: spec, Values: q.Key(item)})
+	}
+
+	//  A     B     C			D			E
+	//  |-----|-----|-----|-----|
+
+	// When we receive a next cursor, direction = A->E
+	// When we receive a prev cursor, direction = E->A
+
+	switch {
+
+	// *If there are no results we don't have to compute the cursors
+	case len(items) == 0:
+
+	// *On A, direction A->E (going forward), return only next cursor, if every item doesn't fit in the page
+	case cursors.Prev == "" && cursors.Next == "":
+		if rowsLeft > len(items) {
+			nextCursor = encode(cursor.Next, items[len(items)-1])
+		}
+
+	// *On E, direction A->E (going forward), return only prev cursor
+	case cursors.Next != "" && rowsLeft == len(items):
+		prevCursor = encode(cursor.Prev, items[0])
+
+	// *On A, direction E->A (going backward), return only next cursor
+	case cursors.Prev != "" && rowsLeft == len(items):
+		nextCursor = encode(cursor.Next, items[len(items)-1])
+
+	// *On E, direction E->A (going backward), return only prev cursor
+	case cursors.Prev != "" && total == rowsLeft:
+		prevCursor = encode(cursor.Prev, items[0])
+
+	// *Somewhere in the middle
+	default:
+		nextCursor = encode(cursor.Next, items[len(items)-1])
+		prevCursor = encode(cursor.Prev, items[0])
+
+	}
+
+	return Page[T]{Items: items, Cursors: Cursors{Prev: prevCursor, Next: nextCursor}}, nil
+}
+
+// decode returns the sort key values of the token, which must be a cursor of dir for the sort spec with n columns.
+func decode(codec cursor.Codec, token string, dir cursor.Direction, spec string, n int) ([]interface{}, error) {
+	c, err := codec.Decode(token)
+	if err != nil {
+		return nil, err
+	}
+	if c.Direction != dir {
+		return nil, &cursor.InvalidError{Reason: fmt.Sprintf("%s cursor used as a %s cursor", c.Direction, dir)}
+	}
+	if c.Sort != spec {
+		return nil, &cursor.InvalidError{Reason: fmt.Sprintf("cursor of sort %q used for %q", c.Sort, spec)}
+	}
+	if len(c.Values) != n {
+		return nil, &cursor.InvalidError{Reason: "malformed sort key values"}
+	}
+
+	keys := make([]interface{}, n)
+	for i, v := range c.Values {
+		keys[i] = v
+	}
+	return keys, nil
+}
+
+// sortSpec is the sort of cols, e.g. "created_at DESC, id DESC", which cursors remember.
+func sortSpec(cols []Column) string {
+	ss := make([]string, len(cols))
+	for i, c := range cols {
+		ss[i] = c.String()
+	}
+	return strings.Join(ss, ", ")
+}
+
+func reverse(cols []Column) []Column {
+	reversed := make([]Column, len(cols))
+	for i, c := range cols {
+		reversed[i] = Column{Name: c.Name, Desc: !c.Desc}
+	}
+	return reversed
+}
+
+// after returns the condition of the rows that come after the sort key values in the placeholders
+// from $n in the order of cols. Columns sorted in the same direction are compared as a row value,
+// e.g. (created_at, id) < ($1, $2), which Postgres can use an index on (created_at, id) for.
+// Mixed directions are expanded, e.g. name > $1 OR (name = $1 AND id < $2).
+func after(cols []Column, n int) string {
+	op := func(c Column) string {
+		if c.Desc {
+			return "<"
+		}
+		return ">"
+	}
+
+	sameDir := true
+	names := make([]string, len(cols))
+	params := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+		params[i] = fmt.Sprintf("$%d", n+i)
+		sameDir = sameDir && c.Desc == cols[0].Desc
+	}
+	if sameDir {
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(names, ", "), op(cols[0]), strings.Join(params, ", "))
+	}
+
+	ors := make([]string, len(cols))
+	for i, c := range cols {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = %s", names[j], params[j]))
+		}
+		ands = append(ands, fmt.Sprintf("%s %s %s", c.Name, op(c), params[i]))
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+	return "(" + strings.Join(ors, " OR ") + ")"
+}
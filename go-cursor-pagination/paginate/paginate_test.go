@@ -0,0 +1,92 @@
+package paginate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"code.com/paginate"
+	"code.com/paginate/cursor"
+)
+
+var codec = cursor.Codec{Key: []byte("0123456789abcdef0123456789abcdef")}
+
+var names = paginate.Query[string]{
+	SQL:  "SELECT name FROM users",
+	Sort: []paginate.Column{{Name: "name"}, {Name: "id", Desc: true}},
+	Scan: func(scan func(dest ...interface{}) error) (string, error) {
+		var n string
+		err := scan(&n)
+		return n, err
+	},
+	Key: func(n string) []string { return []string{n} },
+}
+
+func TestFetch_Invalid(t *testing.T) {
+	token := func(dir cursor.Direction, sort string, values ...string) string {
+		return codec.Encode(cursor.Cursor{Direction: dir, Sort: sort, Values: values})
+	}
+
+	testCases := []struct {
+		desc        string
+		cursors     paginate.Cursors
+		limit       int
+		expectedErr string
+		invalid     bool
+	}{
+		{
+			desc:        "zero limit",
+			expectedErr: "limit cannot be zero",
+		},
+		{
+			desc:        "both cursors",
+			cursors:     paginate.Cursors{Prev: "a", Next: "b"},
+			limit:       5,
+			expectedErr: "two cursors cannot be provided at the same time",
+		},
+		{
+			desc:        "forged",
+			cursors:     paginate.Cursors{Next: cursor.Codec{Key: []byte("forged-key")}.Encode(cursor.Cursor{Direction: cursor.Next})},
+			limit:       5,
+			expectedErr: "invalid cursor. bad signature",
+			invalid:     true,
+		},
+		{
+			desc:        "prev cursor used as next cursor",
+			cursors:     paginate.Cursors{Next: token(cursor.Prev, "name ASC, id DESC", "mert", "1")},
+			limit:       5,
+			expectedErr: "invalid cursor. prev cursor used as a next cursor",
+			invalid:     true,
+		},
+		{
+			desc:        "another sort",
+			cursors:     paginate.Cursors{Prev: token(cursor.Prev, "name DESC, id DESC", "mert", "1")},
+			limit:       5,
+			expectedErr: `invalid cursor. cursor of sort "name DESC, id DESC" used for "name ASC, id DESC"`,
+			invalid:     true,
+		},
+		{
+			desc:        "too few values",
+			cursors:     paginate.Cursors{Next: token(cursor.Next, "name ASC, id DESC", "mert")},
+			limit:       5,
+			expectedErr: "invalid cursor. malformed sort key values",
+			invalid:     true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// every cursor is rejected before the database is queried.
+			page, err := paginate.Fetch(context.TODO(), nil, codec, names, tC.cursors, tC.limit)
+			if err == nil || err.Error() != tC.expectedErr {
+				t.Errorf("Expected error to be %q. Got %v", tC.expectedErr, err)
+			}
+			var invalid *cursor.InvalidError
+			if errors.As(err, &invalid) != tC.invalid {
+				t.Errorf("Expected the error to be a *cursor.InvalidError: %v. Got %T", tC.invalid, err)
+			}
+			if page.Items == nil || len(page.Items) != 0 {
+				t.Errorf("Expected an empty page. Got %+v", page)
+			}
+		})
+	}
+}
@@ -3,132 +3,94 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"code.com/paginate"
+	"code.com/paginate/cursor"
 	"code.com/product"
 )
 
 type Store struct {
-	db *sql.DB
+	db      *sql.DB
+	cursors cursor.Codec
 }
 
-func NewStore(db *sql.DB) Store {
-	return Store{db: db}
+// NewStore initiates a store whose cursors are encoded with cursors.
+func NewStore(db *sql.DB, cursors cursor.Codec) Store {
+	return Store{db: db, cursors: cursors}
 }
 
-type Cursors struct {
-	Prev string
-	Next string
+// Sort is a sort order of the products. Products with the same value of Field are sorted by id in the same
+// direction, so keyset pagination never skips or repeats them.
+type Sort struct {
+	Field string // One of name, created_at and id
+	Desc  bool
 }
 
-func (store Store) GetProducts(
-	ctx context.Context, cursors Cursors, limit int,
-) ([]product.Product, Cursors, error) {
-	if limit == 0 {
-		return []product.Product{}, Cursors{}, errors.New("limit cannot be zero")
-	}
-	if cursors.Next != "" && cursors.Prev != "" {
-		return []product.Product{}, Cursors{}, errors.New("two cursors cannot be provided at the same time")
-	}
-
-	values := make([]interface{}, 0, 4)
-	rowsLeftQuery := "SELECT COUNT(*) FROM products p"
-	pagination := ""
+// DefaultSort sorts the products by creation date, newest first.
+var DefaultSort = Sort{Field: "created_at", Desc: true}
 
-	// Going forward
-	if cursors.Next != "" {
-		rowsLeftQuery += " WHERE p.created_at < $1"
-		pagination += "WHERE p.created_at < $1 ORDER BY created_at DESC LIMIT $2"
-		values = append(values, cursors.Next, limit)
-	}
+// sortKeys are the fields products can be sorted by, and the values of those fields as text Postgres can cast.
+var sortKeys = map[string]func(p product.Product) string{
+	"name":       func(p product.Product) string { return p.Name },
+	"created_at": func(p product.Product) string { return p.CreatedAt.UTC().Format(time.RFC3339Nano) },
+	"id":         func(p product.Product) string { return strconv.Itoa(int(p.ID)) },
+}
 
-	// Going backward
-	if cursors.Prev != "" {
-		rowsLeftQuery += " WHERE p.created_at > $1"
-		pagination += "WHERE p.created_at > $1 ORDER BY created_at ASC LIMIT $2"
-		values = append(values, cursors.Prev, limit)
+// ParseSort parses a sort order like "name", or "-created_at" for descending order.
+func ParseSort(s string) (Sort, error) {
+	sort := Sort{Field: strings.TrimPrefix(s, "-"), Desc: strings.HasPrefix(s, "-")}
+	if _, ok := sortKeys[sort.Field]; !ok {
+		return Sort{}, fmt.Errorf("can't sort by %q, only by name, created_at or id", sort.Field)
 	}
+	return sort, nil
+}
 
-	// No cursors: Going forward from the beginning
-	if cursors.Next == "" && cursors.Prev == "" {
-		pagination = " ORDER BY p.created_at DESC LIMIT $1"
-		values = append(values, limit)
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
 	}
+	return s.Field
+}
 
-	stmt := fmt.Sprintf(`
-		WITH p AS (
-			SELECT * FROM products p %s
-		)
-		SELECT id, created_at, name, 
-		(%s) AS rows_left,
-		(SELECT COUNT(*) FROM products) AS total
-		FROM p
-		ORDER BY created_at DESC
-  `, pagination, rowsLeftQuery)
-
-	rows, err := store.db.Query(stmt, values...)
-	if err != nil {
-		return nil, Cursors{}, fmt.Errorf("failed to get products. error: %v", err)
+// productsQuery returns the query that pages through the products sorted by s, then by id.
+func productsQuery(s Sort) paginate.Query[product.Product] {
+	cols := []paginate.Column{{Name: s.Field, Desc: s.Desc}}
+	keys := []func(product.Product) string{sortKeys[s.Field]}
+	if s.Field != "id" {
+		cols = append(cols, paginate.Column{Name: "id", Desc: s.Desc})
+		keys = append(keys, sortKeys["id"])
 	}
-	defer rows.Close()
-
-	var (
-		rowsLeft int
-		total    int
-		pp       = []product.Product{}
-	)
 
-	for rows.Next() {
-		var p product.Product
-
-		err := rows.Scan(&p.ID, &p.CreatedAt, &p.Name, &rowsLeft, &total)
-		if err != nil {
-			return []product.Product{}, Cursors{}, err
-		}
-
-		pp = append(pp, p)
+	return paginate.Query[product.Product]{
+		SQL:  "SELECT id, created_at, name FROM products",
+		Sort: cols,
+		Scan: func(scan func(dest ...interface{}) error) (product.Product, error) {
+			var p product.Product
+			err := scan(&p.ID, &p.CreatedAt, &p.Name)
+			return p, err
+		},
+		Key: func(p product.Product) []string {
+			values := make([]string, len(keys))
+			for i, key := range keys {
+				values[i] = key(p)
+			}
+			return values
+		},
 	}
+}
 
-	var (
-		prevCursor string // cursor we return when there is a previous page
-		nextCursor string // cursor we return when there is a next page
-	)
-
-	//  A     B     C			D			E
-	//  |-----|-----|-----|-----|
-
-	// When we receive a next cursor, direction = A->E
-	// When we receive a prev cursor, direction = E->A
-
-	switch {
-
-	// *If there are no results we don't have to compute the cursors
-	case rowsLeft < 0:
-
-	// *On A, direction A->E (going forward), return only next cursor
-	case cursors.Prev == "" && cursors.Next == "":
-		nextCursor = pp[len(pp)-1].CreatedAt.UTC().Format(time.RFC3339)
-
-	// *On E, direction A->E (going forward), return only prev cursor
-	case cursors.Next != "" && rowsLeft == len(pp):
-		prevCursor = pp[0].CreatedAt.UTC().Format(time.RFC3339)
-
-	// *On A, direction E->A (going backward), return only next cursor
-	case cursors.Prev != "" && rowsLeft == len(pp):
-		nextCursor = pp[len(pp)-1].CreatedAt.UTC().Format(time.RFC3339)
-
-	// *On E, direction E->A (going backward), return only prev cursor
-	case cursors.Prev != "" && total == rowsLeft:
-		prevCursor = pp[0].CreatedAt.UTC().Format(time.RFC3339)
-
-	// *Somewhere in the middle
-	default:
-		nextCursor = pp[len(pp)-1].CreatedAt.UTC().Format(time.RFC3339)
-		prevCursor = pp[0].CreatedAt.UTC().Format(time.RFC3339)
-
+// GetProducts returns a page of limit products in the order of sort and the cursors of the pages around it,
+// see paginate.Fetch. Cursors are only valid for the sort of the page they were returned with.
+func (store Store) GetProducts(
+	ctx context.Context, sort Sort, cursors paginate.Cursors, limit int,
+) (paginate.Page[product.Product], error) {
+	// the field goes into the SQL, so sorts that weren't parsed are checked too.
+	if _, ok := sortKeys[sort.Field]; !ok {
+		return paginate.Page[product.Product]{Items: []product.Product{}}, fmt.Errorf("can't sort by %q, only by name, created_at or id", sort.Field)
 	}
-
-	return pp, Cursors{Prev: prevCursor, Next: nextCursor}, nil
+	return paginate.Fetch(ctx, store.db, store.cursors, productsQuery(sort), cursors, limit)
 }
@@ -2,8 +2,14 @@ package postgres_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"code.com/paginate"
+	"code.com/paginate/cursor"
 	"code.com/postgres"
 	"code.com/product"
 	"code.com/test"
@@ -15,7 +21,7 @@ func TestGetProducts_FirstPage_Limit5(t *testing.T) {
 	db := test.SetupDB(t)
 	test.CreateProductTable(t, db)
 
-	store := postgres.NewStore(db)
+	store := postgres.NewStore(db, codec)
 
 	_, err := db.Exec(`
 		INSERT INTO products(created_at, name)
@@ -33,18 +39,19 @@ func TestGetProducts_FirstPage_Limit5(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	cursors := postgres.Cursors{} // passing empty cursors, meaning we want the first page
+	cursors := paginate.Cursors{} // passing empty cursors, meaning we want the first page
 	limit := 5
 
-	pp, nextCursors, err := store.GetProducts(context.TODO(), cursors, limit)
+	page, err := store.GetProducts(context.TODO(), postgres.DefaultSort, cursors, limit)
 	if err != nil {
 		t.Fatal(err)
 	}
+	pp, nextCursors := page.Items, readable(t, page.Cursors)
 
 	// we shouldn't get any `Prev` cursor because there is no previous page, we are on the
-	// first page. We should get a cursor for the next page, which should be the creation data
-	// of the last row we receive
-	expectedCursors := postgres.Cursors{Next: "2022-05-26T13:29:16Z"}
+	// first page. We should get a cursor for the next page, which should hold the creation date
+	// and the id of the last row we receive
+	expectedCursors := paginate.Cursors{Next: "2022-05-26T13:29:16Z,4"}
 
 	if diff := cmp.Diff(expectedCursors, nextCursors); diff != "" {
 		t.Errorf("cursors are different (-want +got):\n%s", diff)
@@ -69,7 +76,7 @@ func TestGetProducts(t *testing.T) {
 	db := test.SetupDB(t)
 	test.CreateProductTable(t, db)
 
-	store := postgres.NewStore(db)
+	store := postgres.NewStore(db, codec)
 
 	_, err := db.Exec(`
 		INSERT INTO products(created_at, name)
@@ -89,10 +96,10 @@ func TestGetProducts(t *testing.T) {
 
 	testCases := []struct {
 		desc             string
-		cursors          postgres.Cursors
+		cursors          paginate.Cursors
 		limit            int
 		expectedProducts []product.Product
-		expectedCursors  postgres.Cursors
+		expectedCursors  paginate.Cursors
 	}{
 		{
 			desc:  "first page limit 5",
@@ -104,94 +111,95 @@ func TestGetProducts(t *testing.T) {
 				{Name: "Socks"},
 				{Name: "Pants"},
 			},
-			expectedCursors: postgres.Cursors{
-				Next: "2022-05-26T13:29:16Z",
+			expectedCursors: paginate.Cursors{
+				Next: "2022-05-26T13:29:16Z,4",
 			},
 		},
 		{
 			desc:  "Next page limit 3",
 			limit: 3,
-			cursors: postgres.Cursors{
-				Next: "2022-05-28T13:29:16Z",
+			cursors: paginate.Cursors{
+				Next: "2022-05-28T13:29:16Z,6",
 			},
 			expectedProducts: []product.Product{
 				{Name: "Socks"},
 				{Name: "Pants"},
 				{Name: "T-Shirt"},
 			},
-			expectedCursors: postgres.Cursors{
-				Prev: "2022-05-27T13:29:16Z",
-				Next: "2022-05-25T13:29:16Z",
+			expectedCursors: paginate.Cursors{
+				Prev: "2022-05-27T13:29:16Z,5",
+				Next: "2022-05-25T13:29:16Z,3",
 			},
 		},
 		{
 			desc:  "going forward last page limit 3",
 			limit: 3,
-			cursors: postgres.Cursors{
-				Next: "2022-05-26T13:29:16Z",
+			cursors: paginate.Cursors{
+				Next: "2022-05-26T13:29:16Z,4",
 			},
 			expectedProducts: []product.Product{
 				{Name: "T-Shirt"},
 				{Name: "Polo"},
 				{Name: "Shirt"},
 			},
-			expectedCursors: postgres.Cursors{
-				Prev: "2022-05-25T13:29:16Z",
+			expectedCursors: paginate.Cursors{
+				Prev: "2022-05-25T13:29:16Z,3",
 			},
 		},
 		{
 			desc:  "Go back first page limit 3",
 			limit: 3,
-			cursors: postgres.Cursors{
-				Prev: "2022-05-22T13:29:16Z",
+			cursors: paginate.Cursors{
+				Prev: "2022-05-22T13:29:16Z,0",
 			},
 			expectedProducts: []product.Product{
 				{Name: "T-Shirt"},
 				{Name: "Polo"},
 				{Name: "Shirt"},
 			},
-			expectedCursors: postgres.Cursors{
-				Prev: "2022-05-25T13:29:16Z",
+			expectedCursors: paginate.Cursors{
+				Prev: "2022-05-25T13:29:16Z,3",
 			},
 		},
 		{
 			desc:  "Go back limit 3",
 			limit: 3,
-			cursors: postgres.Cursors{
-				Prev: "2022-05-24T13:29:16Z",
+			cursors: paginate.Cursors{
+				Prev: "2022-05-24T13:29:16Z,2",
 			},
 			expectedProducts: []product.Product{
 				{Name: "Socks"},
 				{Name: "Pants"},
 				{Name: "T-Shirt"},
 			},
-			expectedCursors: postgres.Cursors{
-				Next: "2022-05-25T13:29:16Z",
-				Prev: "2022-05-27T13:29:16Z",
+			expectedCursors: paginate.Cursors{
+				Next: "2022-05-25T13:29:16Z,3",
+				Prev: "2022-05-27T13:29:16Z,5",
 			},
 		},
 		{
 			desc:  "Go back last page limit 3",
 			limit: 3,
-			cursors: postgres.Cursors{
-				Prev: "2022-05-27T13:29:16Z",
+			cursors: paginate.Cursors{
+				Prev: "2022-05-27T13:29:16Z,5",
 			},
 			expectedProducts: []product.Product{
 				{Name: "Glasses"},
 				{Name: "Hat"},
 				{Name: "Shoes"},
 			},
-			expectedCursors: postgres.Cursors{
-				Next: "2022-05-28T13:29:16Z",
+			expectedCursors: paginate.Cursors{
+				Next: "2022-05-28T13:29:16Z,6",
 			},
 		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
-			pp, nextCursors, err := store.GetProducts(context.TODO(), tC.cursors, tC.limit)
+			page, err := store.GetProducts(context.TODO(), postgres.DefaultSort, signed(tC.cursors), tC.limit)
 			if err != nil {
 				t.Fatal(err)
 			}
+			pp, nextCursors := page.Items, readable(t, page.Cursors)
 
 			if diff := cmp.Diff(tC.expectedCursors, nextCursors); diff != "" {
 				t.Errorf("cursors are different (-want +got):\n%s", diff)
@@ -204,3 +212,335 @@ func TestGetProducts(t *testing.T) {
 		})
 	}
 }
+
+func TestGetProducts_IdenticalTimestamps(t *testing.T) {
+	db := test.SetupDB(t)
+	test.CreateProductTable(t, db)
+
+	store := postgres.NewStore(db, codec)
+
+	// 4 products per timestamp, and 10 more created by a single statement, which all get the same now().
+	_, err := db.Exec(`
+		INSERT INTO products(created_at, name)
+		SELECT '2022-05-23 13:29:16'::timestamptz + (i / 4) * interval '1 day', 'Product ' || i
+		FROM generate_series(1, 14) i
+		ORDER BY i
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO products(name)
+		SELECT 'Product ' || i FROM generate_series(15, 24) i ORDER BY i
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// newest first, and the last inserted first among the ones created at the same time.
+	var expected []string
+	for i := 24; i >= 1; i-- {
+		expected = append(expected, fmt.Sprintf("Product %d", i))
+	}
+
+	for _, limit := range []int{1, 3, 4, 5, 24, 25} {
+		t.Run(fmt.Sprintf("forward limit %d", limit), func(t *testing.T) {
+			if diff := cmp.Diff(expected, names(walkForward(t, store, postgres.DefaultSort, limit))); diff != "" {
+				t.Errorf("products are different (-want +got):\n%s", diff)
+			}
+		})
+		t.Run(fmt.Sprintf("backward limit %d", limit), func(t *testing.T) {
+			if diff := cmp.Diff(expected, names(walkBackward(t, store, postgres.DefaultSort, limit))); diff != "" {
+				t.Errorf("products are different (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetProducts_Sorts(t *testing.T) {
+	db := test.SetupDB(t)
+	test.CreateProductTable(t, db)
+
+	store := postgres.NewStore(db, codec)
+
+	// products with the same name or creation date are sorted by id, in the same direction.
+	_, err := db.Exec(`
+		INSERT INTO products(created_at, name)
+		VALUES
+			('2022-05-24 13:29:16', 'Shirt'),
+			('2022-05-23 13:29:16', 'Polo'),
+			('2022-05-24 13:29:16', 'Hat'),
+			('2022-05-23 13:29:16', 'Shirt'),
+			('2022-05-25 13:29:16', 'Polo'),
+			('2022-05-24 13:29:16', 'Shirt'),
+			('2022-05-23 13:29:16', 'Hat')
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		sort        string
+		expectedIDs []product.ID
+	}{
+		{sort: "name", expectedIDs: []product.ID{3, 7, 2, 5, 1, 4, 6}},
+		{sort: "-name", expectedIDs: []product.ID{6, 4, 1, 5, 2, 7, 3}},
+		{sort: "created_at", expectedIDs: []product.ID{2, 4, 7, 1, 3, 6, 5}},
+		{sort: "-created_at", expectedIDs: []product.ID{5, 6, 3, 1, 7, 4, 2}},
+		{sort: "id", expectedIDs: []product.ID{1, 2, 3, 4, 5, 6, 7}},
+		{sort: "-id", expectedIDs: []product.ID{7, 6, 5, 4, 3, 2, 1}},
+	}
+	for _, tC := range testCases {
+		sort, err := postgres.ParseSort(tC.sort)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, limit := range []int{1, 2, 3, 7} {
+			t.Run(fmt.Sprintf("%s forward limit %d", tC.sort, limit), func(t *testing.T) {
+				if diff := cmp.Diff(tC.expectedIDs, ids(walkForward(t, store, sort, limit))); diff != "" {
+					t.Errorf("products are different (-want +got):\n%s", diff)
+				}
+			})
+			t.Run(fmt.Sprintf("%s backward limit %d", tC.sort, limit), func(t *testing.T) {
+				if diff := cmp.Diff(tC.expectedIDs, ids(walkBackward(t, store, sort, limit))); diff != "" {
+					t.Errorf("products are different (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		s           string
+		expected    postgres.Sort
+		expectedErr string
+	}{
+		{desc: "ascending", s: "name", expected: postgres.Sort{Field: "name"}},
+		{desc: "descending", s: "-created_at", expected: postgres.Sort{Field: "created_at", Desc: true}},
+		{desc: "id", s: "-id", expected: postgres.Sort{Field: "id", Desc: true}},
+		{desc: "not allowed", s: "price", expectedErr: `can't sort by "price", only by name, created_at or id`},
+		{desc: "sql", s: "name; DROP TABLE products", expectedErr: `can't sort by "name; DROP TABLE products", only by name, created_at or id`},
+		{desc: "empty", s: "-", expectedErr: `can't sort by "", only by name, created_at or id`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			sort, err := postgres.ParseSort(tC.s)
+			if tC.expectedErr != "" {
+				if err == nil || err.Error() != tC.expectedErr {
+					t.Errorf("Expected error to be %q. Got %v", tC.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tC.expected, sort); diff != "" {
+				t.Errorf("sorts are different (-want +got):\n%s", diff)
+			}
+			if sort.String() != tC.s {
+				t.Errorf("Expected the sort to print as %s. Got %s", tC.s, sort)
+			}
+		})
+	}
+}
+
+func TestGetProducts_SortNotAllowed(t *testing.T) {
+	store := postgres.NewStore(nil, codec)
+	_, err := store.GetProducts(context.TODO(), postgres.Sort{Field: "price"}, paginate.Cursors{}, 5)
+	expectedErr := `can't sort by "price", only by name, created_at or id`
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error to be %q. Got %v", expectedErr, err)
+	}
+}
+
+// walkForward returns every product in the order of sort, following the next cursors from the first page.
+func walkForward(t *testing.T, store postgres.Store, sort postgres.Sort, limit int) []product.Product {
+	t.Helper()
+
+	var pp []product.Product
+	cursors := paginate.Cursors{}
+	for i := 0; i < 100; i++ {
+		page, err := store.GetProducts(context.TODO(), sort, cursors, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pp = append(pp, page.Items...)
+		if page.Cursors.Next == "" {
+			return pp
+		}
+		cursors = paginate.Cursors{Next: page.Cursors.Next}
+	}
+	t.Fatal("the next cursors never ran out")
+	return nil
+}
+
+// walkBackward returns every product in the order of sort, following the prev cursors from the last page.
+func walkBackward(t *testing.T, store postgres.Store, sort postgres.Sort, limit int) []product.Product {
+	t.Helper()
+
+	get := func(cursors paginate.Cursors) paginate.Page[product.Product] {
+		t.Helper()
+
+		page, err := store.GetProducts(context.TODO(), sort, cursors, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page
+	}
+
+	// the last page, reached with the next cursors.
+	page := get(paginate.Cursors{})
+	for i := 0; page.Cursors.Next != ""; i++ {
+		if i == 100 {
+			t.Fatal("the next cursors never ran out")
+		}
+		page = get(paginate.Cursors{Next: page.Cursors.Next})
+	}
+
+	pp := page.Items
+	for i := 0; page.Cursors.Prev != ""; i++ {
+		if i == 100 {
+			t.Fatal("the prev cursors never ran out")
+		}
+		page = get(paginate.Cursors{Prev: page.Cursors.Prev})
+		pp = append(append([]product.Product{}, page.Items...), pp...)
+	}
+	return pp
+}
+
+func names(pp []product.Product) []string {
+	names := make([]string, len(pp))
+	for i, p := range pp {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func ids(pp []product.Product) []product.ID {
+	ids := make([]product.ID, len(pp))
+	for i, p := range pp {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func TestGetProducts_InvalidCursor(t *testing.T) {
+	store := postgres.NewStore(nil, codec)
+	forged := cursor.Codec{Key: []byte("forged-key")}
+	expired := cursor.Codec{Key: codec.Key, TTL: time.Minute, Now: func() time.Time { return time.Now().Add(-time.Hour) }}
+	next := func(c cursor.Codec, sort string, values ...string) string {
+		return c.Encode(cursor.Cursor{Direction: cursor.Next, Sort: sort, Values: values})
+	}
+	valid := next(codec, productsSort, "2022-05-26T13:29:16Z", "4")
+
+	testCases := []struct {
+		desc        string
+		sort        postgres.Sort
+		cursors     paginate.Cursors
+		expectedErr string
+	}{
+		{
+			desc:        "not base64",
+			sort:        postgres.DefaultSort,
+			cursors:     paginate.Cursors{Next: "2022-05-26T13:29:16Z,4"},
+			expectedErr: "invalid cursor. malformed token",
+		},
+		{
+			desc:        "tampered with",
+			sort:        postgres.DefaultSort,
+			cursors:     paginate.Cursors{Next: valid[:len(valid)-2] + "xx"},
+			expectedErr: "invalid cursor. bad signature",
+		},
+		{
+			desc:        "signed with another key",
+			sort:        postgres.DefaultSort,
+			cursors:     paginate.Cursors{Next: next(forged, productsSort, "2022-05-26T13:29:16Z", "4")},
+			expectedErr: "invalid cursor. bad signature",
+		},
+		{
+			desc:        "expired",
+			sort:        postgres.DefaultSort,
+			cursors:     paginate.Cursors{Next: next(expired, productsSort, "2022-05-26T13:29:16Z", "4")},
+			expectedErr: "invalid cursor. expired",
+		},
+		{
+			desc:        "next cursor used as prev cursor",
+			sort:        postgres.DefaultSort,
+			cursors:     paginate.Cursors{Prev: valid},
+			expectedErr: "invalid cursor. next cursor used as a prev cursor",
+		},
+		{
+			desc:        "another sort",
+			sort:        postgres.DefaultSort,
+			cursors:     paginate.Cursors{Next: next(codec, "name ASC", "Shirt")},
+			expectedErr: `invalid cursor. cursor of sort "name ASC" used for "created_at DESC, id DESC"`,
+		},
+		{
+			desc:        "reused under another sort",
+			sort:        postgres.Sort{Field: "name"},
+			cursors:     paginate.Cursors{Next: valid},
+			expectedErr: `invalid cursor. cursor of sort "created_at DESC, id DESC" used for "name ASC, id ASC"`,
+		},
+		{
+			desc:        "reused under another direction",
+			sort:        postgres.Sort{Field: "created_at"},
+			cursors:     paginate.Cursors{Next: valid},
+			expectedErr: `invalid cursor. cursor of sort "created_at DESC, id DESC" used for "created_at ASC, id ASC"`,
+		},
+		{
+			desc:        "timestamp only",
+			sort:        postgres.DefaultSort,
+			cursors:     paginate.Cursors{Next: next(codec, productsSort, "2022-05-26T13:29:16Z")},
+			expectedErr: "invalid cursor. malformed sort key values",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := store.GetProducts(context.TODO(), tC.sort, tC.cursors, 5)
+			var invalid *cursor.InvalidError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Expected a *cursor.InvalidError. Got %v", err)
+			}
+			if err.Error() != tC.expectedErr {
+				t.Errorf("Expected error to be %q. Got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
+
+const productsSort = "created_at DESC, id DESC"
+
+var codec = cursor.Codec{Key: []byte("0123456789abcdef0123456789abcdef"), TTL: time.Hour}
+
+// signed returns the cursors with their "created_at,id" positions encoded like the store encodes them.
+func signed(c paginate.Cursors) paginate.Cursors {
+	encode := func(dir cursor.Direction, pos string) string {
+		if pos == "" {
+			return ""
+		}
+		return codec.Encode(cursor.Cursor{Direction: dir, Sort: productsSort, Values: strings.Split(pos, ",")})
+	}
+	return paginate.Cursors{Prev: encode(cursor.Prev, c.Prev), Next: encode(cursor.Next, c.Next)}
+}
+
+// readable returns the "created_at,id" positions of the cursors returned by the store.
+func readable(t *testing.T, c paginate.Cursors) paginate.Cursors {
+	t.Helper()
+
+	decode := func(dir cursor.Direction, token string) string {
+		if token == "" {
+			return ""
+		}
+		cur, err := codec.Decode(token)
+		if err != nil {
+			t.Fatalf("failed to decode the %s cursor. %v", dir, err)
+		}
+		if cur.Direction != dir || cur.Sort != productsSort {
+			t.Errorf("Expected a %s cursor of %q. Got %+v", dir, productsSort, cur)
+		}
+		return strings.Join(cur.Values, ",")
+	}
+	return paginate.Cursors{Prev: decode(cursor.Prev, c.Prev), Next: decode(cursor.Next, c.Next)}
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"database/sql"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -13,6 +14,7 @@ import (
 // SetupDB sets up a database connection to be used in tests.
 // It creates a new schema with the t.Name().
 // Once the test is complete, it will drop the created schema and close the db connection.
+// The test fails if the database is not reachable, unless it runs with -short.
 func SetupDB(t *testing.T) *sql.DB {
 	t.Helper()
 
@@ -26,16 +28,16 @@ func SetupDB(t *testing.T) *sql.DB {
 	if err != nil {
 		t.Fatalf("db initialization failed. err: %v", err)
 	}
+	defer db.Close()
 
-	schemaName := strings.ToLower(t.Name())
-
-	t.Cleanup(func() {
-		_, err := db.Exec("DROP SCHEMA " + schemaName + " CASCADE")
-		if err != nil {
-			t.Fatalf("db cleanup failed. err: %v", err)
+	if err := db.Ping(); err != nil {
+		if testing.Short() {
+			t.Skipf("database is not reachable, skipping in short mode. err: %v", err)
 		}
-		db.Close()
-	})
+		t.Fatalf("database is not reachable, run `docker-compose up -d`. err: %v", err)
+	}
+
+	schemaName := strings.ToLower(t.Name())
 
 	// create test schema
 	_, err = db.Exec("CREATE SCHEMA " + schemaName)
@@ -43,13 +45,29 @@ func SetupDB(t *testing.T) *sql.DB {
 		t.Fatalf("schema creation failed. err: %v", err)
 	}
 
-	// use schema
-	_, err = db.Exec("SET search_path TO " + schemaName)
+	// use schema on every connection of the pool, not just the one a SET would run on
+	schemaURL, err := url.Parse(dbURL)
 	if err != nil {
-		t.Fatalf("error while switching to schema. err: %v", err)
+		t.Fatalf("invalid database url. err: %v", err)
 	}
+	q := schemaURL.Query()
+	q.Set("search_path", schemaName)
+	schemaURL.RawQuery = q.Encode()
+
+	schemaDB, err := sql.Open("pgx", schemaURL.String())
+	if err != nil {
+		t.Fatalf("db initialization failed. err: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_, err := schemaDB.Exec("DROP SCHEMA " + schemaName + " CASCADE")
+		if err != nil {
+			t.Fatalf("db cleanup failed. err: %v", err)
+		}
+		schemaDB.Close()
+	})
 
-	return db
+	return schemaDB
 }
 
 func CreateProductTable(t *testing.T, db *sql.DB) {
@@ -58,9 +76,11 @@ func CreateProductTable(t *testing.T, db *sql.DB) {
 	_, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS products(
 			id bigserial unique primary key,
-			created_at timestamptz default now(),
+			created_at timestamptz not null default now(),
 			name varchar not null
-		)
+		);
+		CREATE INDEX IF NOT EXISTS products_created_at_id_idx ON products (created_at, id);
+		CREATE INDEX IF NOT EXISTS products_name_id_idx ON products (name, id)
 	`)
 	if err != nil {
 		t.Fatalf("failed to create product table. error: %v", err)
@@ -1,8 +1,12 @@
 module code.com
 
-go 1.17
+go 1.18
 
-require github.com/jackc/pgx/v4 v4.16.0
+require (
+	code.com/paginate v0.0.0-00010101000000-000000000000
+	github.com/google/go-cmp v0.5.8
+	github.com/jackc/pgx/v4 v4.16.0
+)
 
 require (
 	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
@@ -15,3 +19,5 @@ require (
 	golang.org/x/crypto v0.0.0-20220427172511-eb4f295cb31f // indirect
 	golang.org/x/text v0.3.7 // indirect
 )
+
+replace code.com/paginate => ../go-cursor-pagination/paginate
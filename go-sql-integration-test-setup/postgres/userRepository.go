@@ -3,6 +3,9 @@ package postgres
 import (
 	"context"
 	"database/sql"
+
+	"code.com/paginate"
+	"code.com/paginate/cursor"
 )
 
 type DB interface {
@@ -12,11 +15,13 @@ type DB interface {
 }
 
 type UserRepo struct {
-	db DB
+	db      DB
+	cursors cursor.Codec
 }
 
-func NewUserRepo(db DB) UserRepo {
-	return UserRepo{db: db}
+// NewUserRepo initiates a user repo whose cursors are encoded with cursors.
+func NewUserRepo(db DB, cursors cursor.Codec) UserRepo {
+	return UserRepo{db: db, cursors: cursors}
 }
 
 func (r UserRepo) UserExists(ctx context.Context, ID int) (bool, error) {
@@ -30,22 +35,20 @@ func (r UserRepo) UserExists(ctx context.Context, ID int) (bool, error) {
 	return exists, nil
 }
 
-func (r UserRepo) GetAll(ctx context.Context) ([]string, error) {
-	var nn []string
-
-	rows, err := r.db.QueryContext(ctx, `SELECT name from users`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
+// userNames pages through the names of the users in alphabetical order. Names are unique, so they are the sort key.
+var userNames = paginate.Query[string]{
+	SQL:  "SELECT name FROM users",
+	Sort: []paginate.Column{{Name: "name"}},
+	Scan: func(scan func(dest ...interface{}) error) (string, error) {
 		var n string
-		if err := rows.Scan(&n); err != nil {
-			return nil, err
-		}
-		nn = append(nn, n)
-	}
+		err := scan(&n)
+		return n, err
+	},
+	Key: func(n string) []string { return []string{n} },
+}
 
-	return nn, nil
+// GetAll returns a page of limit user names in alphabetical order and the cursors of the pages around it,
+// see paginate.Fetch.
+func (r UserRepo) GetAll(ctx context.Context, cursors paginate.Cursors, limit int) (paginate.Page[string], error) {
+	return paginate.Fetch(ctx, r.db, r.cursors, userNames, cursors, limit)
 }
@@ -4,14 +4,17 @@ import (
 	"context"
 	"testing"
 
+	"code.com/paginate"
+	"code.com/paginate/cursor"
 	"code.com/postgres"
 	"code.com/test"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestUserExists(t *testing.T) {
 	db := test.SetupTX(t)
 	createUsersTable(t, db)
-	repo := postgres.NewUserRepo(db)
+	repo := postgres.NewUserRepo(db, codec)
 
 	if _, err := db.Exec(`INSERT INTO users(name) VALUES ('mert')`); err != nil {
 		t.Fatalf("failed to insert user. %v", err)
@@ -30,7 +33,7 @@ func TestUserExists(t *testing.T) {
 func TestGetAll(t *testing.T) {
 	db := test.SetupTX(t)
 	createUsersTable(t, db)
-	repo := postgres.NewUserRepo(db)
+	repo := postgres.NewUserRepo(db, codec)
 
 	_, err := db.Exec(`
 		INSERT INTO users(name) VALUES ('mert'), ('m'), ('t')
@@ -39,16 +42,39 @@ func TestGetAll(t *testing.T) {
 		t.Fatalf("failed to insert user. %v", err)
 	}
 
-	uu, err := repo.GetAll(context.TODO())
+	page, err := repo.GetAll(context.TODO(), paginate.Cursors{}, 2)
 	if err != nil {
 		t.Fatalf("GetAll() = %v", err)
 	}
+	if diff := cmp.Diff([]string{"m", "mert"}, page.Items); diff != "" {
+		t.Errorf("users are different (-want +got):\n%s", diff)
+	}
+	if page.Cursors.Prev != "" || page.Cursors.Next == "" {
+		t.Fatalf("Expected only a next cursor. Got %+v", page.Cursors)
+	}
+
+	page, err = repo.GetAll(context.TODO(), paginate.Cursors{Next: page.Cursors.Next}, 2)
+	if err != nil {
+		t.Fatalf("GetAll() = %v", err)
+	}
+	if diff := cmp.Diff([]string{"t"}, page.Items); diff != "" {
+		t.Errorf("users are different (-want +got):\n%s", diff)
+	}
+	if page.Cursors.Prev == "" || page.Cursors.Next != "" {
+		t.Fatalf("Expected only a prev cursor. Got %+v", page.Cursors)
+	}
 
-	if uu[1] != "mert" && uu[2] != "m" && uu[2] != "t" {
-		t.Errorf("mismatch. %v", uu)
+	page, err = repo.GetAll(context.TODO(), paginate.Cursors{Prev: page.Cursors.Prev}, 2)
+	if err != nil {
+		t.Fatalf("GetAll() = %v", err)
+	}
+	if diff := cmp.Diff([]string{"m", "mert"}, page.Items); diff != "" {
+		t.Errorf("users are different (-want +got):\n%s", diff)
 	}
 }
 
+var codec = cursor.Codec{Key: []byte("0123456789abcdef0123456789abcdef")}
+
 func createUsersTable(t *testing.T, db postgres.DB) {
 	t.Helper()
 
@@ -13,6 +13,7 @@ import (
 // SetupDB sets up a database connection to be used in tests.
 // It creates a new schema with the t.Name().
 // Once the test is complete, it will drop the created schema and close the db connection.
+// The test fails if the database is not reachable, unless it runs with -short.
 func SetupDB(t *testing.T) *sql.DB {
 	t.Helper()
 
@@ -27,6 +28,14 @@ func SetupDB(t *testing.T) *sql.DB {
 		t.Fatalf("db initialization failed. err: %v", err)
 	}
 
+	if err := db.Ping(); err != nil {
+		db.Close()
+		if testing.Short() {
+			t.Skipf("database is not reachable, skipping in short mode. err: %v", err)
+		}
+		t.Fatalf("database is not reachable, run `docker-compose up -d`. err: %v", err)
+	}
+
 	schemaName := strings.ToLower(t.Name())
 
 	t.Cleanup(func() {
@@ -54,6 +63,7 @@ func SetupDB(t *testing.T) *sql.DB {
 
 // SetupTX sets up a database transaction to be used in tests.
 // Once the tests are done it will rollback the transaction
+// The test fails if the database is not reachable, unless it runs with -short.
 func SetupTX(t *testing.T) *sql.Tx {
 	t.Helper()
 
@@ -68,6 +78,14 @@ func SetupTX(t *testing.T) *sql.Tx {
 		t.Fatalf("Failed to initialize db. Err: %s", err.Error())
 	}
 
+	if err := db.Ping(); err != nil {
+		db.Close()
+		if testing.Short() {
+			t.Skipf("database is not reachable, skipping in short mode. err: %v", err)
+		}
+		t.Fatalf("database is not reachable, run `docker-compose up -d`. err: %v", err)
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		t.Fatalf("Unable to begin tx. %v", err)